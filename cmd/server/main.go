@@ -5,7 +5,9 @@ import (
 	"golang-rest-api-template/pkg/api"
 	"golang-rest-api-template/pkg/cache"
 	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/jobs"
 	"log"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -35,6 +37,10 @@ import (
 // @in header
 // @name X-API-Key
 
+// @securityDefinitions.apikey RequestSignature
+// @in header
+// @name X-Signature
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
@@ -46,10 +52,16 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	jobs.StartTrashPurger(ctx, db, api.NewCoverStore(), logger, jobs.TrashRetention(), time.Hour)
+	jobs.StartExportCleaner(ctx, db, logger, time.Hour)
+	jobs.StartImportReportCleaner(ctx, api.ImportReportDir(), logger, api.ImportReportTTL, time.Hour)
+	exportRepository := api.NewExportRepository(dbWrapper, &ctx)
+	exportRepository.StartWorker(ctx, time.Minute)
+
 	//gin.SetMode(gin.ReleaseMode)
 	gin.SetMode(gin.DebugMode)
 
-	r := api.NewRouter(logger, mongo, dbWrapper, redisClient, &ctx)
+	r := api.NewRouter(logger, mongo, dbWrapper, redisClient, exportRepository, &ctx)
 
 	if err := r.Run(":8001"); err != nil {
 		log.Fatal(err)
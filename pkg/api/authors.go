@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuthorRepository interface {
+	FindAuthors(c *gin.Context)
+	CreateAuthor(c *gin.Context)
+	FindAuthor(c *gin.Context)
+	UpdateAuthor(c *gin.Context)
+	DeleteAuthor(c *gin.Context)
+	AuthorBooks(c *gin.Context)
+}
+
+// authorRepository manages the people credited for books
+type authorRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+}
+
+func NewAuthorRepository(db database.Database, ctx *context.Context) *authorRepository {
+	return &authorRepository{
+		DB:  db,
+		Ctx: ctx,
+	}
+}
+
+// authoredBook is a book with the role of the author it was listed for
+type authoredBook struct {
+	models.Book
+	Role     string `json:"role"`
+	Position int    `json:"position"`
+}
+
+// FindAuthors godoc
+// @Summary List authors
+// @Description List authors by name, optionally only those whose name contains a text
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name query string false "Text the name contains, case insensitive"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Author "Authors"
+// @Failure 400 {string} string "Bad Request"
+// @Router /authors [get]
+func (r *authorRepository) FindAuthors(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := r.DB.Order("name, id")
+	if name := strings.ToLower(strings.TrimSpace(c.Query("name"))); name != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, "%"+escapeLike(name)+"%")
+	}
+	authors := []models.Author{}
+	if err := db.Offset(offset).Limit(limit).Find(&authors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": authors})
+}
+
+// CreateAuthor godoc
+// @Summary Create an author
+// @Description Create an author. Names that only differ in case, spacing or punctuation from an existing author are rejected
+// @Tags authors
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param input body models.CreateAuthor true "Author"
+// @Success 201 {object} models.Author "Created author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {object} models.Author "Existing author with the same name"
+// @Router /authors [post]
+func (r *authorRepository) CreateAuthor(c *gin.Context) {
+	var input models.CreateAuthor
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !r.checkAuthorName(c, input.Name, 0) {
+		return
+	}
+
+	author := models.Author{Name: strings.TrimSpace(input.Name), Bio: input.Bio}
+	if err := r.DB.Create(&author).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": author})
+}
+
+// checkAuthorName responds with a conflict if another author than id has the same name key
+func (r *authorRepository) checkAuthorName(c *gin.Context, name string, id uint) bool {
+	key := models.NameKey(name)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must contain letters or digits"})
+		return false
+	}
+
+	var existing models.Author
+	err := r.DB.Where("name_key = ? AND id <> ?", key, id).First(&existing).Error()
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "An author with this name already exists", "data": existing})
+		return false
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return false
+	}
+	return true
+}
+
+// FindAuthor godoc
+// @Summary Find an author by ID
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Author ID"
+// @Success 200 {object} models.Author "Author"
+// @Failure 404 {string} string "author not found"
+// @Router /authors/{id} [get]
+func (r *authorRepository) FindAuthor(c *gin.Context) {
+	var author models.Author
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&author).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "author not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": author})
+}
+
+// UpdateAuthor godoc
+// @Summary Update an author by ID
+// @Description Replace the name and bio of an author. The bylines of their books are left as they are
+// @Tags authors
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Author ID"
+// @Param input body models.CreateAuthor true "Author"
+// @Success 200 {object} models.Author "Updated author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "author not found"
+// @Failure 409 {object} models.Author "Existing author with the same name"
+// @Router /authors/{id} [put]
+func (r *authorRepository) UpdateAuthor(c *gin.Context) {
+	var author models.Author
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&author).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "author not found"})
+		return
+	}
+
+	var input models.CreateAuthor
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !r.checkAuthorName(c, input.Name, author.ID) {
+		return
+	}
+
+	updated := author
+	updated.Name = strings.TrimSpace(input.Name)
+	updated.NameKey = models.NameKey(input.Name)
+	updated.Bio = input.Bio
+	if err := r.DB.Model(&author).Select("name", "name_key", "bio").Updates(&updated).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": author})
+}
+
+// DeleteAuthor godoc
+// @Summary Delete an author by ID
+// @Description Delete an author who is not credited for any book
+// @Tags authors
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Param id path string true "Author ID"
+// @Success 204 {string} string "Successfully deleted author"
+// @Failure 404 {string} string "author not found"
+// @Failure 409 {string} string "The author is credited for books"
+// @Router /authors/{id} [delete]
+func (r *authorRepository) DeleteAuthor(c *gin.Context) {
+	var author models.Author
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&author).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "author not found"})
+		return
+	}
+
+	var credits int64
+	if err := r.DB.Model(&models.BookAuthor{}).Where("author_id = ?", author.ID).Count(&credits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author"})
+		return
+	}
+	if credits > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The author is credited for books"})
+		return
+	}
+
+	if err := r.DB.Delete(&author).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"data": true})
+}
+
+// AuthorBooks godoc
+// @Summary List the books of an author
+// @Description List the books an author is credited for with their role, oldest first. Books in the trash are left out
+// @Tags authors
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Author ID"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} authoredBook "Books with the role of the author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "author not found"
+// @Router /authors/{id}/books [get]
+func (r *authorRepository) AuthorBooks(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var author models.Author
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&author).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "author not found"})
+		return
+	}
+
+	books := []authoredBook{}
+	err = r.DB.Model(&models.Book{}).
+		Select("books.*, books_authors.role, books_authors.position").
+		Joins("JOIN books_authors ON books_authors.book_id = books.id").
+		Where("books_authors.author_id = ?", author.ID).
+		Order("books.published_at, books.id, books_authors.role").
+		Offset(offset).Limit(limit).
+		Find(&books).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": books})
+}
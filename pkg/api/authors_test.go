@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// expectAuthor makes the next First load author
+func expectAuthor(mockDB *database.MockDatabase, author models.Author) {
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Author) = author
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+}
+
+func TestNameKey(t *testing.T) {
+	assert.Equal(t, models.NameKey("J.R.R. Tolkien"), models.NameKey("j. r. r. tolkien"))
+	assert.Equal(t, "gabrielgarcíamárquez", models.NameKey("Gabriel García Márquez"))
+	assert.Equal(t, "", models.NameKey(" - "))
+}
+
+func TestCreateAuthorConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewAuthorRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("name_key = ? AND id <> ?", "jrrtolkien", uint(0)).Return(mockDB)
+	expectAuthor(mockDB, models.Author{ID: 7, Name: "J. R. R. Tolkien"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/authors", repo.CreateAuthor)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/authors", strings.NewReader(`{"name": "J.R.R. Tolkien"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":7`)
+}
+
+func TestDeleteAuthorCredited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewAuthorRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "7").Return(mockDB)
+	expectAuthor(mockDB, models.Author{ID: 7, Name: "Frank Herbert"})
+
+	var sql string
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		*tx.Statement.Dest.(*int64) = 2
+		tx.RowsAffected = 1
+	})
+	mockDB.EXPECT().Model(&models.BookAuthor{}).Return(db.Model(&models.BookAuthor{}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/authors/:id", repo.DeleteAuthor)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/authors/7", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, `SELECT count(*) FROM "books_authors" WHERE author_id = $1`, sql)
+}
+
+func TestAuthorBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewAuthorRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "7").Return(mockDB)
+	expectAuthor(mockDB, models.Author{ID: 7, Name: "Frank Herbert"})
+
+	var sql string
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:statement", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	mockDB.EXPECT().Model(&models.Book{}).Return(db.Model(&models.Book{}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/authors/:id/books", repo.AuthorBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/authors/7/books", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// Books in the trash are left out
+	assert.Equal(t, `SELECT books.*, books_authors.role, books_authors.position FROM "books" JOIN books_authors ON books_authors.book_id = books.id `+
+		`WHERE books_authors.author_id = $1 AND "books"."deleted_at" IS NULL ORDER BY books.published_at, books.id, books_authors.role LIMIT $2`, sql)
+}
+
+func TestSetBookAuthorsRepeatedCredit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert"}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/books/:id/authors", repo.SetBookAuthors)
+
+	// The role defaults to author, so both entries are the same credit
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/books/1/authors", strings.NewReader(`{"authors": [{"author_id": 7}, {"author_id": 7, "role": "author"}]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error": "Author 7 is credited as author twice"}`, w.Body.String())
+}
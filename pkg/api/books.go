@@ -3,23 +3,50 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"golang-rest-api-template/pkg/cache"
 	"golang-rest-api-template/pkg/database"
 	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/search"
+	"golang-rest-api-template/pkg/storage"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type BookRepository interface {
 	Healthcheck(c *gin.Context)
 	FindBooks(c *gin.Context)
+	SearchBooks(c *gin.Context)
+	SuggestBooks(c *gin.Context)
 	CreateBook(c *gin.Context)
+	BulkBooks(c *gin.Context)
+	ImportBooks(c *gin.Context)
+	ImportReport(c *gin.Context)
+	ExportBooks(c *gin.Context)
 	FindBook(c *gin.Context)
 	UpdateBook(c *gin.Context)
+	PatchBook(c *gin.Context)
 	DeleteBook(c *gin.Context)
+	TrashedBooks(c *gin.Context)
+	RestoreBook(c *gin.Context)
+	PurgeBook(c *gin.Context)
+	BookHistory(c *gin.Context)
+	RevertBook(c *gin.Context)
+	FindDuplicates(c *gin.Context)
+	MergeBooks(c *gin.Context)
+	BookAuthors(c *gin.Context)
+	SetBookAuthors(c *gin.Context)
+	FindWork(c *gin.Context)
+	WorkEditions(c *gin.Context)
+	UpdateWork(c *gin.Context)
+	MergeWorks(c *gin.Context)
+	SplitWork(c *gin.Context)
+	UploadCover(c *gin.Context)
+	DeleteCover(c *gin.Context)
+	FindCover(c *gin.Context)
 }
 
 // bookRepository holds shared resources like database and Redis client
@@ -27,14 +54,25 @@ type bookRepository struct {
 	DB          database.Database
 	RedisClient cache.Cache
 	Ctx         *context.Context
+	Suggestions *search.Index
+	// RequireIfMatch rejects writes without an If-Match header
+	RequireIfMatch bool
+	// Blobs stores cover images
+	Blobs storage.BlobStore
+	// ImportReportDir is where the reports of imports are written
+	ImportReportDir string
 }
 
 // NewAppContext creates a new AppContext
 func NewBookRepository(db database.Database, redisClient cache.Cache, ctx *context.Context) *bookRepository {
 	return &bookRepository{
-		DB:          db,
-		RedisClient: redisClient,
-		Ctx:         ctx,
+		DB:              db,
+		RedisClient:     redisClient,
+		Ctx:             ctx,
+		Suggestions:     search.NewIndex(),
+		RequireIfMatch:  requireIfMatch(),
+		Blobs:           NewCoverStore(),
+		ImportReportDir: ImportReportDir(),
 	}
 }
 
@@ -55,65 +93,163 @@ func (r *bookRepository) Healthcheck(c *gin.Context) {
 
 // FindBooks godoc
 // @Summary Get all books with pagination
-// @Description Get a list of all books with optional pagination
+// @Description Get a list of all books with optional pagination, filtering and sorting
 // @Tags books
 // @Security ApiKeyAuth
 // @Produce json
 // @Param offset query int false "Offset for pagination" default(0)
-// @Param limit query int false "Limit for pagination" default(10)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor, empty for the first page. Replaces offset"
+// @Param count query bool false "Include the total number of matching books"
+// @Param facets query string false "Comma separated facets to count matching books by (author, decade, genre, language)"
+// @Param facet_limit query int false "Values per facet, at most 50" default(10)
+// @Param author query string false "Author, case insensitive"
+// @Param title query string false "Text the title contains, case insensitive"
+// @Param genre query string false "Genre, case insensitive"
+// @Param language query string false "Language, case insensitive"
+// @Param decade query int false "Decade of publication, e.g. 1990"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_after query string false "Updated at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_before query string false "Updated before (RFC 3339 or YYYY-MM-DD)"
+// @Param ids query string false "Comma separated list of book IDs"
+// @Param publisher_id query int false "ID of the publisher books are linked to"
+// @Param series_id query int false "ID of the series books belong to"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending (id, title, author, created_at, updated_at)" default(id)
 // @Success 200 {array} models.Book "Successfully retrieved list of books"
+// @Param If-None-Match header string false "ETag of the cached page"
+// @Header 200 {string} Link "Links to the first, prev, next and last pages (RFC 8288)"
+// @Header 200 {string} ETag "Entity tag of the page"
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {string} string "Bad Request"
 // @Router /books [get]
 func (r *bookRepository) FindBooks(c *gin.Context) {
-	var books []models.Book
-
-	// Get query params
-	offsetQuery := c.DefaultQuery("offset", "0")
-	limitQuery := c.DefaultQuery("limit", "10")
+	query, err := parseBookQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Convert query params to integers
-	offset, err := strconv.Atoi(offsetQuery)
+	params, err := parsePageParams(c, query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	limit, err := strconv.Atoi(limitQuery)
+	facets, err := parseFacetParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Create a cache key based on query params
-	cacheKey := "books_offset_" + offsetQuery + "_limit_" + limitQuery
+	cacheKey := params.cacheKey(query)
 
 	// Try fetching the data from Redis first
-	cachedBooks, err := r.RedisClient.Get(*r.Ctx, cacheKey).Result()
+	var page bookPage
+	cachedPage, err := r.RedisClient.Get(*r.Ctx, cacheKey).Result()
 	if err == nil {
-		err := json.Unmarshal([]byte(cachedBooks), &books)
-		if err != nil {
+		if err := json.Unmarshal([]byte(cachedPage), &page); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal cached data"})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"data": books})
-		return
+	} else {
+		// If cache missed, fetch data from the database
+		if err := r.loadBookPage(query, params, &page); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+			return
+		}
+
+		// Serialize the page and store it in Redis
+		serializedPage, err := json.Marshal(page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal data"})
+			return
+		}
+		err = r.RedisClient.Set(*r.Ctx, cacheKey, serializedPage, time.Minute).Err()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set cache"})
+			return
+		}
 	}
 
-	// If cache missed, fetch data from the database
-	r.DB.Offset(offset).Limit(limit).Find(&books)
+	// Facets are cached on their own as they do not depend on the page
+	if facets != nil {
+		page.Facets, err = r.loadFacets(facets, query.normalized(), query.filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count facets"})
+			return
+		}
+	}
 
-	// Serialize books object and store it in Redis
-	serializedBooks, err := json.Marshal(books)
+	body, err := json.Marshal(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal data"})
 		return
 	}
-	err = r.RedisClient.Set(*r.Ctx, cacheKey, serializedBooks, time.Minute).Err() // Here TTL is set to one hour
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set cache"})
+
+	// Pages have no meaningful modification time as deleted books leave no trace, so only the ETag validates them
+	c.Header("Link", linkHeader(c.Request.URL, params, &page))
+	setCacheValidators(c, contentETag(body), time.Time{})
+	if notModified(c, contentETag(body), time.Time{}) {
 		return
 	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// loadBookPage fetches one row more than the limit to find out whether
+// another page follows
+func (r *bookRepository) loadBookPage(query *bookQuery, params *pageParams, page *bookPage) error {
+	books := []models.Book{}
+	scopes := []func(*gorm.DB) *gorm.DB{query.filter}
+	backward := params.Cursor != nil && params.Cursor.Prev
+	if params.Cursor != nil {
+		scopes = append(scopes, query.keyset(params.Cursor))
+	}
+	if backward {
+		scopes = append(scopes, query.reverseOrder)
+	} else {
+		scopes = append(scopes, query.order)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": books})
+	db := r.DB.Scopes(scopes...)
+	if !params.CursorMode {
+		db = db.Offset(params.Offset)
+	}
+	if err := db.Limit(params.Limit + 1).Find(&books).Error; err != nil {
+		return err
+	}
+
+	page.HasMore = len(books) > params.Limit
+	if page.HasMore {
+		books = books[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(books)-1; i < j; i, j = i+1, j-1 {
+			books[i], books[j] = books[j], books[i]
+		}
+	}
+	page.Data = books
+
+	if params.CursorMode && len(books) > 0 {
+		// Going back, the page we came from always follows
+		if page.HasMore || backward {
+			page.NextCursor = newCursor(query, books[len(books)-1], false)
+		}
+		if params.Cursor != nil && (!backward || page.HasMore) {
+			page.PrevCursor = newCursor(query, books[0], true)
+		}
+		page.HasMore = page.NextCursor != ""
+	}
+
+	if params.Count {
+		var total int64
+		if err := r.DB.Model(&models.Book{}).Scopes(query.filter).Count(&total).Error; err != nil {
+			return err
+		}
+		page.Total = &total
+	}
+	return nil
 }
 
 // CreateBook godoc
@@ -128,6 +264,7 @@ func (r *bookRepository) FindBooks(c *gin.Context) {
 // @Success 201 {object} models.Book "Successfully created book"
 // @Failure 400 {string} string "Bad Request"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "ISBN-13 already used by another book"
 // @Router /books [post]
 func (r *bookRepository) CreateBook(c *gin.Context) {
 	appCtx, exists := c.MustGet("appCtx").(*bookRepository)
@@ -137,25 +274,90 @@ func (r *bookRepository) CreateBook(c *gin.Context) {
 	}
 	var input models.CreateBook
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindBook(c, &input); err != nil {
+		writeBookError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	book := models.Book{Title: input.Title, Author: input.Author}
-
-	appCtx.DB.Create(&book)
+	book := newBook(input)
+	revision := newRevision(c, models.RevisionCreate)
+	err := appCtx.DB.Transaction(func(tx *gorm.DB) error {
+		return insertBook(tx, &book, revision)
+	})
+	if status, problems := rejectedBook(err); problems != nil {
+		writeBookError(c, status, problems)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
+		return
+	}
 
 	// Invalidate cache
-	keysPattern := "books_offset_*"
-	keys, err := appCtx.RedisClient.Keys(*appCtx.Ctx, keysPattern).Result()
-	if err == nil {
+	appCtx.invalidateBookCache()
+	appCtx.Suggestions.Invalidate()
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusCreated, gin.H{"data": book})
+}
+
+// newBook returns the first version of a book created from input
+func newBook(input models.CreateBook) models.Book {
+	return models.Book{
+		Title:        input.Title,
+		Author:       input.Author,
+		Genre:        input.Genre,
+		Language:     input.Language,
+		PublishedAt:  input.PublishedAt,
+		ISBN10:       input.ISBN10,
+		ISBN13:       input.ISBN13,
+		Publisher:    input.Publisher,
+		PageCount:    input.PageCount,
+		Description:  input.Description,
+		Edition:      input.Edition,
+		SeriesID:     input.SeriesID,
+		SeriesVolume: input.SeriesVolume,
+		Version:      1,
+	}
+}
+
+// insertBook creates book within the transaction tx as the only edition of a
+// new work, records its first revision and credits it to the author of its byline
+func insertBook(tx *gorm.DB, book *models.Book, revision models.BookRevision) error {
+	if err := linkPublisher(tx, book); err != nil {
+		return err
+	}
+	if err := checkSeries(tx, book.SeriesID); err != nil {
+		return err
+	}
+	work := models.Work{Title: book.Title}
+	if err := tx.Create(&work).Error; err != nil {
+		return err
+	}
+	book.WorkID = &work.ID
+	if err := tx.Create(book).Error; err != nil {
+		return translateBookError(err)
+	}
+	if err := recordRevision(tx, revision, nil, *book); err != nil {
+		return err
+	}
+	return linkBylineAuthor(tx, *book)
+}
+
+// bookCachePatterns match the cached book listings, suggestions and facet counts
+var bookCachePatterns = []string{"books_offset_*", "books_cursor_*", "books_suggest_*", "books_facets_*"}
+
+// invalidateBookCache drops everything cached about books
+func (r *bookRepository) invalidateBookCache() {
+	for _, pattern := range bookCachePatterns {
+		keys, err := r.RedisClient.Keys(*r.Ctx, pattern).Result()
+		if err != nil {
+			continue
+		}
 		for _, key := range keys {
-			appCtx.RedisClient.Del(*appCtx.Ctx, key)
+			r.RedisClient.Del(*r.Ctx, key)
 		}
 	}
-
-	c.JSON(http.StatusCreated, gin.H{"data": book})
 }
 
 // FindBook godoc
@@ -165,7 +367,12 @@ func (r *bookRepository) CreateBook(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy"
 // @Success 200 {object} models.Book "Successfully retrieved book"
+// @Header 200 {string} ETag "Entity tag of the current version"
+// @Header 200 {string} Last-Modified "Time of the last update"
+// @Success 304 {string} string "Not Modified"
 // @Failure 404 {string} string "Book not found"
 // @Router /books/{id} [get]
 func (r *bookRepository) FindBook(c *gin.Context) {
@@ -176,21 +383,31 @@ func (r *bookRepository) FindBook(c *gin.Context) {
 		return
 	}
 
+	setCacheValidators(c, bookETag(book), book.UpdatedAt)
+	if notModified(c, bookETag(book), book.UpdatedAt) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": book})
 }
 
 // UpdateBook godoc
-// @Summary Update a book by ID
-// @Description Update the book details for the given ID
+// @Summary Replace a book by ID
+// @Description Replace all editable fields of the book with the given ID, fields left out are cleared
 // @Tags books
 // @Security ApiKeyAuth
+// @Security JwtAuth
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param input body models.UpdateBook true "Update book object"
 // @Success 200 {object} models.Book "Successfully updated book"
+// @Header 200 {string} ETag "Entity tag of the new version"
 // @Failure 400 {string} string "Bad Request"
 // @Failure 404 {string} string "book not found"
+// @Failure 409 {string} string "ISBN-13 already used by another book"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 428 {string} string "If-Match header required"
 // @Router /books/{id} [put]
 func (r *bookRepository) UpdateBook(c *gin.Context) {
 	var book models.Book
@@ -201,25 +418,37 @@ func (r *bookRepository) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !r.checkIfMatch(c, book) {
 		return
 	}
 
-	r.DB.Model(&book).Updates(models.Book{Title: input.Title, Author: input.Author})
+	if err := bindBook(c, &input); err != nil {
+		writeBookError(c, http.StatusBadRequest, err)
+		return
+	}
 
+	if err := r.replaceBook(&book, input, newRevision(c, models.RevisionUpdate)); err != nil {
+		writeUpdateError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
 	c.JSON(http.StatusOK, gin.H{"data": book})
 }
 
 // DeleteBook godoc
 // @Summary Delete a book by ID
-// @Description Delete the book with the given ID
+// @Description Move the book with the given ID to the trash, from where it can be restored until it is purged
 // @Tags books
 // @Security ApiKeyAuth
+// @Security JwtAuth
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 204 {string} string "Successfully deleted book"
 // @Failure 404 {string} string "book not found"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 428 {string} string "If-Match header required"
 // @Router /books/{id} [delete]
 func (r *bookRepository) DeleteBook(c *gin.Context) {
 	var book models.Book
@@ -229,7 +458,40 @@ func (r *bookRepository) DeleteBook(c *gin.Context) {
 		return
 	}
 
-	r.DB.Delete(&book)
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	revision := newRevision(c, models.RevisionDelete)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		return trashBook(tx, book, revision)
+	})
+	if errors.Is(err, errVersionConflict) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+		return
+	}
+	r.invalidateBookCache()
+	r.Suggestions.Invalidate()
 
 	c.JSON(http.StatusNoContent, gin.H{"data": true})
 }
+
+// trashBook moves book to the trash within the transaction tx and records
+// revision. It fails with errVersionConflict if the stored version is no
+// longer the one book was read at.
+func trashBook(tx *gorm.DB, book models.Book, revision models.BookRevision) error {
+	// Only delete the version the precondition was checked against
+	result := tx.Delete(&book, "version = ?", book.Version)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	before := editableBook(book)
+	return recordRevision(tx, revision, &before, book)
+}
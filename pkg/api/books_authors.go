@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// linkBylineAuthor credits book to the author its byline names, creating the
+// author if there is none with the same name key yet
+func linkBylineAuthor(tx *gorm.DB, book models.Book) error {
+	author := models.Author{Name: strings.TrimSpace(book.Author)}
+	if models.NameKey(author.Name) == "" {
+		return nil
+	}
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name_key"}}, DoNothing: true}).Create(&author).Error
+	if err != nil {
+		return err
+	}
+	// Nothing is returned if the author already existed
+	if author.ID == 0 {
+		if err := tx.Where("name_key = ?", author.NameKey).First(&author).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Create(&models.BookAuthor{BookID: book.ID, AuthorID: author.ID, Role: models.AuthorRoleAuthor}).Error
+}
+
+// BookAuthors godoc
+// @Summary List the credits of a book
+// @Description List the authors, editors and translators of a book in order
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} models.BookAuthor "Credits with their author"
+// @Failure 404 {string} string "book not found"
+// @Router /books/{id}/authors [get]
+func (r *bookRepository) BookAuthors(c *gin.Context) {
+	var book models.Book
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	credits := []models.BookAuthor{}
+	if err := r.DB.Model(&models.BookAuthor{}).Where("book_id = ?", book.ID).Preload("Author").Order("position").Find(&credits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": credits})
+}
+
+// SetBookAuthors godoc
+// @Summary Replace the credits of a book
+// @Description Replace the authors, editors and translators of a book with an ordered list. The byline in the author field of the book is left as it is
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param input body models.SetBookAuthors true "Credits in order"
+// @Success 200 {array} models.BookAuthor "Credits with their author"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "book not found"
+// @Failure 422 {string} string "Unknown or repeated authors"
+// @Router /books/{id}/authors [put]
+func (r *bookRepository) SetBookAuthors(c *gin.Context) {
+	var book models.Book
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	var input models.SetBookAuthors
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credits := make([]models.BookAuthor, len(input.Authors))
+	ids := make([]uint, 0, len(input.Authors))
+	seen := make(map[models.BookAuthorCredit]bool)
+	for i, credit := range input.Authors {
+		if credit.Role == "" {
+			credit.Role = models.AuthorRoleAuthor
+		}
+		if seen[credit] {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("Author %d is credited as %s twice", credit.AuthorID, credit.Role)})
+			return
+		}
+		seen[credit] = true
+		credits[i] = models.BookAuthor{BookID: book.ID, AuthorID: credit.AuthorID, Role: credit.Role, Position: i}
+		ids = append(ids, credit.AuthorID)
+	}
+
+	var authors []models.Author
+	if err := r.DB.Where("id IN ?", ids).Find(&authors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+	found := make(map[uint]*models.Author, len(authors))
+	for i := range authors {
+		found[authors[i].ID] = &authors[i]
+	}
+	for i := range credits {
+		if credits[i].Author = found[credits[i].AuthorID]; credits[i].Author == nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("Author %d not found", credits[i].AuthorID)})
+			return
+		}
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
+			return err
+		}
+		// The authors exist, so only the credits are written
+		return tx.Omit(clause.Associations).Create(&credits).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": credits})
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkSize bounds the body of a bulk request
+const maxBulkSize = 10 << 20
+
+// errBulkOperationFailed rolls back the transaction of a failed bulk operation
+var errBulkOperationFailed = errors.New("bulk operation failed")
+
+// bulkResult is the outcome of one operation of a bulk request
+type bulkResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Data   *models.Book `json:"data,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	// Fields lists the problems of the invalid fields of the book
+	Fields bookFieldErrors `json:"fields,omitempty"`
+}
+
+// bulkOperation is a validated operation of a bulk request
+type bulkOperation struct {
+	models.BulkBookOperation
+	create models.CreateBook
+	update models.UpdateBook
+}
+
+// parseBulkOperation validates operation like the body of the single book endpoint
+func parseBulkOperation(operation models.BulkBookOperation) (bulkOperation, error) {
+	parsed := bulkOperation{BulkBookOperation: operation}
+	switch operation.Op {
+	case models.BulkCreate:
+		if len(operation.Book) == 0 {
+			return parsed, errors.New("book is required")
+		}
+		return parsed, decodeBook(operation.Book, &parsed.create)
+	case models.BulkUpdate:
+		if operation.ID == 0 {
+			return parsed, errors.New("id is required")
+		}
+		if len(operation.Book) == 0 {
+			return parsed, errors.New("book is required")
+		}
+		return parsed, decodeBook(operation.Book, &parsed.update)
+	case models.BulkDelete:
+		if operation.ID == 0 {
+			return parsed, errors.New("id is required")
+		}
+		return parsed, nil
+	}
+	return parsed, fmt.Errorf("Invalid op: %q", operation.Op)
+}
+
+// BulkBooks godoc
+// @Summary Create, update and delete books in bulk
+// @Description Run up to 1000 create, update (full replacement) and delete operations. Atomic requests run in one transaction and fail as a whole, other requests apply every operation that succeeds. Each operation reports its own status
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param input body models.BulkBooks true "Operations"
+// @Success 200 {array} bulkResult "Results by operation index"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 422 {array} bulkResult "Invalid operations of an atomic request"
+// @Router /books/bulk [post]
+func (r *bookRepository) BulkBooks(c *gin.Context) {
+	var input models.BulkBooks
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkSize)
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operations := make([]bulkOperation, len(input.Operations))
+	results := make([]bulkResult, len(input.Operations))
+	invalid := false
+	for i, operation := range input.Operations {
+		var err error
+		results[i].Index = i
+		if operations[i], err = parseBulkOperation(operation); err != nil {
+			results[i].Status = http.StatusUnprocessableEntity
+			results[i].Error = err.Error()
+			errors.As(err, &results[i].Fields)
+			invalid = true
+		}
+	}
+
+	if input.Atomic {
+		if invalid {
+			skipBulkOperations(results, -1, "Not executed, the request has invalid operations")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"data": results})
+			return
+		}
+
+		failed := -1
+		err := r.DB.Transaction(func(tx *gorm.DB) error {
+			for i, operation := range operations {
+				results[i] = r.applyBulkOperation(c, tx, i, operation)
+				if results[i].Error != "" {
+					failed = i
+					return errBulkOperationFailed
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			status := http.StatusInternalServerError
+			if failed >= 0 {
+				status = results[failed].Status
+			}
+			skipBulkOperations(results, failed, "Rolled back, the request failed")
+			c.JSON(status, gin.H{"data": results})
+			return
+		}
+		r.invalidateBookCache()
+		r.Suggestions.Invalidate()
+		c.JSON(http.StatusOK, gin.H{"data": results})
+		return
+	}
+
+	changed := false
+	for i, operation := range operations {
+		if results[i].Error != "" {
+			continue
+		}
+		err := r.DB.Transaction(func(tx *gorm.DB) error {
+			results[i] = r.applyBulkOperation(c, tx, i, operation)
+			if results[i].Error != "" {
+				return errBulkOperationFailed
+			}
+			return nil
+		})
+		if err == nil {
+			changed = true
+		} else if !errors.Is(err, errBulkOperationFailed) {
+			results[i] = bulkResult{Index: i, Status: http.StatusInternalServerError, Error: "Failed to commit"}
+		}
+	}
+
+	// A single invalidation for the whole request
+	if changed {
+		r.invalidateBookCache()
+		r.Suggestions.Invalidate()
+	}
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// skipBulkOperations marks all results but the failed one as not applied
+func skipBulkOperations(results []bulkResult, failed int, message string) {
+	for i := range results {
+		if i != failed && (failed >= 0 || results[i].Error == "") {
+			results[i] = bulkResult{Index: i, Status: http.StatusFailedDependency, Error: message}
+		}
+	}
+}
+
+// applyBulkOperation runs operation within the transaction tx
+func (r *bookRepository) applyBulkOperation(c *gin.Context, tx *gorm.DB, index int, operation bulkOperation) bulkResult {
+	if operation.Op == models.BulkCreate {
+		book := newBook(operation.create)
+		err := insertBook(tx, &book, newRevision(c, models.RevisionCreate))
+		if status, problems := rejectedBook(err); problems != nil {
+			return rejectedBookResult(index, status, problems)
+		}
+		if err != nil {
+			return bulkResult{Index: index, Status: http.StatusInternalServerError, Error: "Failed to create book"}
+		}
+		return bulkResult{Index: index, Status: http.StatusCreated, Data: &book}
+	}
+
+	var book models.Book
+	if err := tx.Where("id = ?", operation.ID).First(&book).Error; err != nil {
+		return bulkResult{Index: index, Status: http.StatusNotFound, Error: "book not found"}
+	}
+	switch r.ifMatchFailure(operation.IfMatch, book) {
+	case http.StatusPreconditionRequired:
+		return bulkResult{Index: index, Status: http.StatusPreconditionRequired, Error: "if_match required"}
+	case http.StatusPreconditionFailed:
+		return bulkResult{Index: index, Status: http.StatusPreconditionFailed, Error: "Book has been modified"}
+	}
+
+	var err error
+	result := bulkResult{Index: index}
+	if operation.Op == models.BulkUpdate {
+		err = updateBook(tx, &book, operation.update, newRevision(c, models.RevisionUpdate))
+		result.Status, result.Data = http.StatusOK, &book
+	} else {
+		err = trashBook(tx, book, newRevision(c, models.RevisionDelete))
+		result.Status = http.StatusNoContent
+	}
+	if errors.Is(err, errVersionConflict) {
+		return bulkResult{Index: index, Status: http.StatusPreconditionFailed, Error: "Book has been modified"}
+	}
+	if status, problems := rejectedBook(err); problems != nil {
+		return rejectedBookResult(index, status, problems)
+	}
+	if err != nil {
+		return bulkResult{Index: index, Status: http.StatusInternalServerError, Error: "Failed to write book"}
+	}
+	return result
+}
+
+// rejectedBookResult is the result of an operation whose book conflicts with the stored data
+func rejectedBookResult(index, status int, problems bookFieldErrors) bulkResult {
+	return bulkResult{Index: index, Status: status, Error: problems.Error(), Fields: problems}
+}
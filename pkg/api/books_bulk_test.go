@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// bulkTestDB is a dry run database in which no book exists
+func bulkTestDB(t *testing.T) *gorm.DB {
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:not_found", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Book); ok {
+			tx.AddError(gorm.ErrRecordNotFound)
+		}
+	})
+	return db
+}
+
+func serveBulk(t *testing.T, repo *bookRepository, body string) (int, []bulkResult) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/bulk", repo.BulkBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var response struct {
+		Data []bulkResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+	return w.Code, response.Data
+}
+
+func TestBulkBooksInvalidAtomic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), nil, &ctx)
+
+	// Nothing is executed if any operation is invalid
+	status, results := serveBulk(t, repo, `{"atomic": true, "operations": [
+		{"op": "create", "book": {"title": "Dune", "author": "Frank Herbert"}},
+		{"op": "update", "id": 1, "book": {"title": "Dune"}},
+		{"op": "delete"},
+		{"op": "rename", "id": 1}
+	]}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, http.StatusFailedDependency, results[0].Status)
+	for _, result := range results[1:] {
+		assert.Equal(t, http.StatusUnprocessableEntity, result.Status)
+	}
+	assert.Equal(t, bookFieldErrors{"author": "is required"}, results[1].Fields)
+	assert.Equal(t, "id is required", results[2].Error)
+}
+
+func TestBulkBooksBestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	// Every valid operation runs in a transaction of its own
+	db := bulkTestDB(t)
+	expectTransaction(mockDB, db)
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	status, results := serveBulk(t, repo, `{"operations": [
+		{"op": "create", "book": {"title": "Dune", "author": "Frank Herbert"}},
+		{"op": "delete", "id": 5},
+		{"op": "create", "book": {"title": "Dune", "author": "Frank Herbert", "rating": 5}}
+	]}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusCreated, results[0].Status)
+	assert.Equal(t, "Dune", results[0].Data.Title)
+	assert.Equal(t, http.StatusNotFound, results[1].Status)
+	assert.Equal(t, http.StatusUnprocessableEntity, results[2].Status)
+	assert.Contains(t, results[2].Error, "unknown field")
+}
+
+func TestBulkBooksAtomicRollback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	// The cache is left alone as nothing changed
+	expectTransaction(mockDB, bulkTestDB(t))
+
+	status, results := serveBulk(t, repo, `{"atomic": true, "operations": [
+		{"op": "create", "book": {"title": "Dune", "author": "Frank Herbert"}},
+		{"op": "delete", "id": 5},
+		{"op": "delete", "id": 6}
+	]}`)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, []int{http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency},
+		[]int{results[0].Status, results[1].Status, results[2].Status})
+	assert.Nil(t, results[0].Data)
+}
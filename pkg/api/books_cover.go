@@ -0,0 +1,329 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/imaging"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/storage"
+	"image/jpeg"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of cover uploads
+const (
+	maxCoverSize   = 10 << 20
+	maxCoverPixels = 40_000_000
+)
+
+// coverOriginal is the size name of the uploaded cover image
+const coverOriginal = "original"
+
+// coverSizes are the JPEG thumbnails made of a cover, largest first since
+// each one is scaled down from the one before
+var coverSizes = []struct {
+	name  string
+	width int
+}{
+	{"large", 600},
+	{"medium", 300},
+	{"small", 120},
+}
+
+// coverExtensions are the file extensions of cover blobs by content type
+var coverExtensions = map[string]string{
+	imaging.JPEG: ".jpg",
+	imaging.PNG:  ".png",
+	imaging.WebP: ".webp",
+}
+
+// coverContentTypes are the content types of cover blobs by file extension
+var coverContentTypes = map[string]string{
+	".jpg":  imaging.JPEG,
+	".png":  imaging.PNG,
+	".webp": imaging.WebP,
+}
+
+// NewCoverStore returns the store of cover images, a directory set by COVER_DIR
+func NewCoverStore() storage.BlobStore {
+	dir := os.Getenv("COVER_DIR")
+	if dir == "" {
+		dir = "covers"
+	}
+	return storage.NewLocalStore(dir)
+}
+
+// readCover reads the file field of a multipart cover upload. It responds
+// and returns nil if there is none or it is too large.
+func readCover(c *gin.Context) []byte {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCoverSize+64<<10)
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Cover must be at most %d MB", maxCoverSize>>20)})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the cover as the file field of multipart/form-data"})
+		return nil
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover"})
+		return nil
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxCoverSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover"})
+		return nil
+	}
+	if len(data) > maxCoverSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Cover must be at most %d MB", maxCoverSize>>20)})
+		return nil
+	}
+	return data
+}
+
+// coverImages returns the blobs of a cover by size: the upload itself and
+// its thumbnails
+func coverImages(data []byte) (map[string][]byte, error) {
+	images := map[string][]byte{coverOriginal: data}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, size := range coverSizes {
+		thumbnail := imaging.Thumbnail(img, size.width)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		images[size.name] = buf.Bytes()
+		img = thumbnail
+	}
+	return images, nil
+}
+
+// storeCover stores the images of a cover of book under a new prefix and
+// returns their keys by size. Nothing is left behind if it fails.
+func (r *bookRepository) storeCover(c *gin.Context, book models.Book, images map[string][]byte, contentType string) (map[string]string, error) {
+	prefix := fmt.Sprintf("covers/%d/%s/", book.ID, auth.RandomHex(16))
+	keys := make(map[string]string, len(images))
+	for size, data := range images {
+		extension := ".jpg"
+		if size == coverOriginal {
+			extension = coverExtensions[contentType]
+		}
+		keys[size] = prefix + size + extension
+		if err := r.Blobs.Put(c.Request.Context(), keys[size], bytes.NewReader(data)); err != nil {
+			r.deleteCover(c, keys)
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// deleteCover removes the images of a cover, failures only leave unused blobs behind
+func (r *bookRepository) deleteCover(c *gin.Context, keys map[string]string) {
+	for _, key := range keys {
+		if err := r.Blobs.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("Failed to delete cover image %s: %v", key, err)
+		}
+	}
+}
+
+// setCover replaces the cover keys of book and bumps its version. It fails
+// with errVersionConflict if the stored version is no longer the one book
+// was read at.
+func (r *bookRepository) setCover(book *models.Book, keys map[string]string) error {
+	updated := *book
+	updated.CoverKeys = keys
+	updated.Version = book.Version + 1
+
+	model := *book
+	result := r.DB.Model(&model).
+		Where("version = ?", book.Version).
+		Select("cover_keys", "version").
+		Updates(&updated)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	updated.UpdatedAt = model.UpdatedAt
+	updated.SetCoverURLs()
+	*book = updated
+	return nil
+}
+
+// UploadCover godoc
+// @Summary Upload the cover of a book
+// @Description Replace the cover image of a book with a JPEG, PNG or WebP upload of at most 10 MB and 40 megapixels. The type is taken from the content of the file. Covers are also scaled down to large (600 px wide), medium (300 px) and small (120 px) JPEG thumbnails
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being changed"
+// @Param file formData file true "Cover image"
+// @Success 200 {object} models.Book "Book with its cover URLs"
+// @Header 200 {string} ETag "Entity tag of the updated version"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "book not found"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 413 {string} string "Cover too large"
+// @Failure 415 {string} string "Not a JPEG, PNG or WebP image"
+// @Failure 422 {string} string "Corrupt image or too many pixels"
+// @Failure 428 {string} string "If-Match header required"
+// @Router /books/{id}/cover [post]
+func (r *bookRepository) UploadCover(c *gin.Context) {
+	var book models.Book
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	data := readCover(c)
+	if data == nil {
+		return
+	}
+	contentType, err := imaging.Sniff(data)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	// Checking the size first keeps huge images from being decoded
+	width, height, err := imaging.Size(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if width*height > maxCoverPixels {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("Cover must be at most %d megapixels", maxCoverPixels/1_000_000)})
+		return
+	}
+	images, err := coverImages(data)
+	if errors.Is(err, imaging.ErrCorrupt) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale cover"})
+		return
+	}
+
+	keys, err := r.storeCover(c, book, images, contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store cover"})
+		return
+	}
+	previous := book.CoverKeys
+	if err := r.setCover(&book, keys); err != nil {
+		r.deleteCover(c, keys)
+		if errors.Is(err, errVersionConflict) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
+	r.deleteCover(c, previous)
+	r.invalidateBookCache()
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, gin.H{"data": book})
+}
+
+// DeleteCover godoc
+// @Summary Delete the cover of a book
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being changed"
+// @Success 204 {string} string "Successfully deleted cover"
+// @Failure 404 {string} string "book not found or book has no cover"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 428 {string} string "If-Match header required"
+// @Router /books/{id}/cover [delete]
+func (r *bookRepository) DeleteCover(c *gin.Context) {
+	var book models.Book
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+	if len(book.CoverKeys) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book has no cover"})
+		return
+	}
+
+	previous := book.CoverKeys
+	if err := r.setCover(&book, nil); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
+	r.deleteCover(c, previous)
+	r.invalidateBookCache()
+
+	c.Status(http.StatusNoContent)
+}
+
+// FindCover godoc
+// @Summary Download a cover image
+// @Description Download a cover image by the URL listed in the covers of its book. Every upload gets new URLs, so the images can be cached forever
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/webp
+// @Param key path string true "Key of the image below covers/"
+// @Success 200 {file} file "Cover image"
+// @Failure 404 {string} string "cover not found"
+// @Router /covers/{key} [get]
+func (r *bookRepository) FindCover(c *gin.Context) {
+	key := "covers" + c.Param("key")
+	contentType, ok := coverContentTypes[path.Ext(key)]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cover not found"})
+		return
+	}
+
+	blob, size, err := r.Blobs.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cover not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read cover"})
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, size, contentType, blob, nil)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/storage"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// coverUpload builds a multipart request uploading data as the cover of book 1
+func coverUpload(t *testing.T, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	// The claimed type is ignored, the content decides
+	part, err := writer.CreateFormFile("file", "cover.jpg")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/books/1/cover", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadCover(t *testing.T) {
+	var pngCover bytes.Buffer
+	png.Encode(&pngCover, image.NewRGBA(image.Rect(0, 0, 800, 1200)))
+	// Lossless WebP of a single pixel
+	webpCover, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+	for _, test := range []struct {
+		data     []byte
+		original string
+		large    [2]int
+	}{
+		{pngCover.Bytes(), "/original.png", [2]int{600, 900}},
+		// Images are never scaled up
+		{webpCover, "/original.webp", [2]int{1, 1}},
+	} {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		mockCache := cache.NewMockCache(ctrl)
+		ctx := context.Background()
+		repo := NewBookRepository(mockDB, mockCache, &ctx)
+		store := storage.NewLocalStore(t.TempDir())
+		repo.Blobs = store
+
+		expectBook(mockDB, "1", models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Version: 2})
+		var sql string
+		db := dryRunDB(t)
+		db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+			sql = tx.Statement.SQL.String()
+			tx.RowsAffected = 1
+		})
+		mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(func(value interface{}) *gorm.DB {
+			return db.Model(value)
+		})
+		for _, pattern := range bookCachePatterns {
+			mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+		}
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.POST("/books/:id/cover", repo.UploadCover)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, coverUpload(t, test.data))
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, `"1-3"`, w.Header().Get("ETag"))
+		assert.Equal(t, `UPDATE "books" SET "cover_keys"=$1,"version"=$2,"updated_at"=$3 WHERE version = $4 AND "books"."deleted_at" IS NULL AND "id" = $5`, sql)
+
+		var response struct {
+			Data models.Book `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		covers := response.Data.Covers
+		assert.Len(t, covers, 4)
+		assert.True(t, strings.HasSuffix(covers["original"], test.original), covers["original"])
+		assert.True(t, strings.HasPrefix(covers["large"], "/api/v1/covers/1/"), covers["large"])
+
+		// The large thumbnail is a JPEG at most 600 pixels wide
+		blob, _, err := store.Open(ctx, strings.TrimPrefix(covers["large"], models.CoverURL))
+		if assert.NoError(t, err) {
+			config, err := jpeg.DecodeConfig(blob)
+			blob.Close()
+			assert.NoError(t, err)
+			assert.Equal(t, test.large, [2]int{config.Width, config.Height})
+		}
+		ctrl.Finish()
+	}
+}
+
+func TestUploadCoverRejected(t *testing.T) {
+	var huge bytes.Buffer
+	png.Encode(&huge, image.NewGray(image.Rect(0, 0, 8000, 6000)))
+
+	for _, test := range []struct {
+		data   []byte
+		status int
+	}{
+		{[]byte("GIF89a\x01\x00\x01\x00"), http.StatusUnsupportedMediaType},
+		{[]byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f"), http.StatusUnprocessableEntity},
+		{[]byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{[]byte("\x89PNG\r\n\x1a\n\x00\x00"), http.StatusUnprocessableEntity},
+		{huge.Bytes(), http.StatusUnprocessableEntity},
+	} {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		ctx := context.Background()
+		repo := NewBookRepository(mockDB, nil, &ctx)
+		repo.Blobs = storage.NewLocalStore(t.TempDir())
+
+		expectBook(mockDB, "1", models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert"})
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.POST("/books/:id/cover", repo.UploadCover)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, coverUpload(t, test.data))
+
+		assert.Equal(t, test.status, w.Code, w.Body.String())
+		ctrl.Finish()
+	}
+}
+
+func TestFindCover(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBookRepository(nil, nil, &ctx)
+	store := storage.NewLocalStore(t.TempDir())
+	repo.Blobs = store
+	store.Put(ctx, "covers/1/abc/small.jpg", strings.NewReader("jpeg"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/covers/*key", repo.FindCover)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/covers/1/abc/small.jpg", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	data, _ := io.ReadAll(w.Body)
+	assert.Equal(t, "jpeg", string(data))
+
+	for _, path := range []string{"/covers/1/abc/large.jpg", "/covers/1/abc/..%2f..%2fsmall.jpg", "/covers/1/abc/small.txt"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
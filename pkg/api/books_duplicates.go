@@ -0,0 +1,306 @@
+package api
+
+import (
+	"errors"
+	"golang-rest-api-template/pkg/dedupe"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultDuplicateScore is the lowest score of the pairs listed by default
+const defaultDuplicateScore = 0.8
+
+// duplicateCandidatesSQL finds the pairs of books worth comparing: books
+// sharing an ISBN-13 and, unless both have one, books with titles similar
+// by the trigram index. Only these pairs are loaded and scored.
+const duplicateCandidatesSQL = `SELECT a.id AS book_id, b.id AS duplicate_id
+FROM books a JOIN books b ON b.isbn13 = a.isbn13 AND b.id > a.id
+WHERE a.isbn13 <> '' AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+UNION
+SELECT a.id, b.id
+FROM books a JOIN books b ON b.title % a.title AND b.id > a.id
+WHERE (a.isbn13 = '' OR b.isbn13 = '') AND a.deleted_at IS NULL AND b.deleted_at IS NULL`
+
+// parseMinScore reads the lowest score of the duplicate pairs to list
+func parseMinScore(c *gin.Context) (float64, error) {
+	score, err := strconv.ParseFloat(c.DefaultQuery("min_score", strconv.FormatFloat(defaultDuplicateScore, 'f', -1, 64)), 64)
+	if err != nil || score <= 0 || score > 1 {
+		return 0, errors.New("min_score must be a number above 0 and at most 1")
+	}
+	return score, nil
+}
+
+// fillEmptyFields completes the editable fields of a book with those of its
+// duplicate. Fields the book has keep their value.
+func fillEmptyFields(input models.UpdateBook, duplicate models.Book) models.UpdateBook {
+	fill := func(value *string, other string) {
+		if *value == "" {
+			*value = other
+		}
+	}
+	fill(&input.Genre, duplicate.Genre)
+	fill(&input.Language, duplicate.Language)
+	fill(&input.ISBN10, duplicate.ISBN10)
+	fill(&input.ISBN13, duplicate.ISBN13)
+	fill(&input.Publisher, duplicate.Publisher)
+	fill(&input.Description, duplicate.Description)
+	fill(&input.Edition, duplicate.Edition)
+	if input.PublishedAt == nil {
+		input.PublishedAt = duplicate.PublishedAt
+	}
+	if input.PageCount == 0 {
+		input.PageCount = duplicate.PageCount
+	}
+	// The volume only means something in its series
+	if input.SeriesID == nil {
+		input.SeriesID = duplicate.SeriesID
+		input.SeriesVolume = duplicate.SeriesVolume
+	}
+	return input
+}
+
+// moveCredits moves the author credits of the book with ID from to the book
+// with ID to, after its own credits. Credits both books have are kept once.
+func moveCredits(tx *gorm.DB, to, from uint) error {
+	var kept, moved []models.BookAuthor
+	if err := tx.Where("book_id = ?", to).Find(&kept).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", from).Order("position").Find(&moved).Error; err != nil {
+		return err
+	}
+
+	credited := make(map[models.BookAuthorCredit]bool, len(kept))
+	position := 0
+	for _, credit := range kept {
+		credited[models.BookAuthorCredit{AuthorID: credit.AuthorID, Role: credit.Role}] = true
+		position = max(position, credit.Position+1)
+	}
+	var added []models.BookAuthor
+	for _, credit := range moved {
+		key := models.BookAuthorCredit{AuthorID: credit.AuthorID, Role: credit.Role}
+		if credited[key] {
+			continue
+		}
+		credited[key] = true
+		added = append(added, models.BookAuthor{BookID: to, AuthorID: credit.AuthorID, Role: credit.Role, Position: position})
+		position++
+	}
+
+	if err := tx.Where("book_id = ?", from).Delete(&models.BookAuthor{}).Error; err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&added).Error
+}
+
+// moveCover gives book the cover of duplicate. The duplicate lets go of it,
+// so purging the duplicate from the trash leaves the images alone.
+func moveCover(tx *gorm.DB, book *models.Book, duplicate models.Book) error {
+	if err := tx.Unscoped().Model(&models.Book{ID: duplicate.ID}).Select("cover_keys").Updates(&models.Book{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Book{ID: book.ID}).Select("cover_keys").Updates(&models.Book{CoverKeys: duplicate.CoverKeys}).Error; err != nil {
+		return err
+	}
+	book.CoverKeys = duplicate.CoverKeys
+	book.SetCoverURLs()
+	return nil
+}
+
+// mergeBook merges duplicate into book within the transaction tx: the
+// duplicate goes to the trash, its credits and work move to book and book
+// gets the fields it was missing, its cover included. Both changes are
+// recorded as revisions naming the other book.
+func mergeBook(tx *gorm.DB, book *models.Book, duplicate models.Book, revision models.BookRevision) error {
+	keptID, duplicateID := book.ID, duplicate.ID
+	input := fillEmptyFields(editableBook(*book), duplicate)
+
+	// The duplicate leaves the unique ISBN-13 index before book takes its ISBN
+	trashed := revision
+	trashed.MergedWith = &keptID
+	if err := trashBook(tx, duplicate, trashed); err != nil {
+		return err
+	}
+	if err := moveCredits(tx, keptID, duplicateID); err != nil {
+		return err
+	}
+	// Being the same book, both are editions of the same work
+	if book.WorkID != nil && duplicate.WorkID != nil && *book.WorkID != *duplicate.WorkID {
+		if err := moveEditions(tx, *book.WorkID, "work_id = ?", *duplicate.WorkID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Work{}, *duplicate.WorkID).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(book.CoverKeys) == 0 && len(duplicate.CoverKeys) > 0 {
+		if err := moveCover(tx, book, duplicate); err != nil {
+			return err
+		}
+	}
+
+	kept := revision
+	kept.MergedWith = &duplicateID
+	return updateBook(tx, book, input, kept)
+}
+
+// findDuplicates returns the pairs of books scoring at least minScore, best
+// first. Postgres finds the candidate pairs by index, other databases only
+// used in development compare all books in memory.
+func (r *bookRepository) findDuplicates(minScore float64) ([]dedupe.Pair, error) {
+	if r.DB.Dialect() != "postgres" {
+		var books []models.Book
+		if err := r.DB.Order("id").Find(&books).Error; err != nil {
+			return nil, err
+		}
+		return dedupe.Find(books, minScore), nil
+	}
+
+	var candidates []dedupe.Candidate
+	if err := r.DB.Raw(duplicateCandidatesSQL).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, candidate := range candidates {
+		for _, id := range []uint{candidate.BookID, candidate.DuplicateID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	var books []models.Book
+	if err := r.DB.Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return dedupe.Score(books, candidates, minScore), nil
+}
+
+// FindDuplicates godoc
+// @Summary List suspected duplicate books
+// @Description List pairs of books that may be the same book entered twice, best match first. Books with the same ISBN-13 score 1, books with different ISBN-13s are editions and are never listed, others score by the similarity of their titles and authors. Only books with similar titles are compared (admins only)
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param min_score query number false "Lowest score of the pairs listed, above 0 and at most 1" default(0.8)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} dedupe.Pair "Suspected duplicates, the older book first"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient role"
+// @Router /books/duplicates [get]
+func (r *bookRepository) FindDuplicates(c *gin.Context) {
+	minScore, err := parseMinScore(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pairs, err := r.findDuplicates(minScore)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	offset = min(offset, len(pairs))
+	page := pairs[offset:min(offset+limit, len(pairs))]
+	if page == nil {
+		page = []dedupe.Pair{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": page})
+}
+
+// MergeBooks godoc
+// @Summary Merge a duplicate into a book
+// @Description Keep the book and move the duplicate to the trash. The book gets the fields and the cover it is missing from the duplicate, the author credits of the duplicate and the other editions of its work. Both books record the merge in their history (admins only)
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the book that is kept"
+// @Param If-Match header string false "ETag of the version of the book being kept"
+// @Param input body models.MergeBooks true "Duplicate"
+// @Success 200 {object} models.Book "Merged book"
+// @Header 200 {string} ETag "Entity tag of the merged version"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient role"
+// @Failure 404 {string} string "book not found"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 422 {string} string "Unknown duplicate or the book itself"
+// @Failure 428 {string} string "If-Match header required"
+// @Router /books/{id}/merge [post]
+func (r *bookRepository) MergeBooks(c *gin.Context) {
+	var book models.Book
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	var input models.MergeBooks
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.DuplicateID == book.ID {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A book cannot be merged into itself"})
+		return
+	}
+
+	var duplicate models.Book
+	err := r.DB.Where("id = ?", input.DuplicateID).First(&duplicate).Error()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Duplicate book not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duplicate"})
+		return
+	}
+
+	revision := newRevision(c, models.RevisionMerge)
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		return mergeBook(tx, &book, duplicate, revision)
+	})
+	if errors.Is(err, errVersionConflict) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge books"})
+		return
+	}
+	r.invalidateBookCache()
+	r.Suggestions.Invalidate()
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, gin.H{"data": book})
+}
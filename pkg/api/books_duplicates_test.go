@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/dedupe"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// expectBook makes the next lookup by ID load book
+func expectBook(mockDB *database.MockDatabase, id interface{}, book models.Book) *gomock.Call {
+	mockDB.EXPECT().Where("id = ?", id).Return(mockDB)
+	call := mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = book
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+	return call
+}
+
+func TestFindDuplicates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	mockDB.EXPECT().Dialect().Return("sqlite").AnyTimes()
+	mockDB.EXPECT().Order("id").Return(dryRunDBWithRows(t, []models.Book{
+		{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"},
+		{ID: 2, Title: "Dune", Author: "Frank Herbert"},
+		{ID: 3, Title: "Hobbit", Author: "Tolkien, J. R. R."},
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/duplicates", repo.FindDuplicates)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/duplicates", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), `"score"`))
+	assert.Contains(t, w.Body.String(), `"score":1,"same_isbn":false`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books/duplicates?min_score=2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFindDuplicatesPostgres(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	var sql string
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:candidates", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		*tx.Statement.Dest.(*[]dedupe.Candidate) = []dedupe.Candidate{{BookID: 1, DuplicateID: 3}, {BookID: 1, DuplicateID: 4}}
+	})
+	mockDB.EXPECT().Dialect().Return("postgres")
+	mockDB.EXPECT().Raw(duplicateCandidatesSQL).DoAndReturn(func(sql string, values ...interface{}) *gorm.DB {
+		return db.Raw(sql, values...)
+	})
+	// Only the books of candidate pairs are loaded
+	mockDB.EXPECT().Where("id IN ?", []uint{1, 3, 4}).Return(mockDB)
+	mockDB.EXPECT().Find(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) *gorm.DB {
+		*dest.(*[]models.Book) = []models.Book{
+			{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"},
+			{ID: 3, Title: "Hobbit", Author: "Tolkien, J. R. R."},
+			{ID: 4, Title: "The Hobbit Companion", Author: "David Day"},
+		}
+		return db
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/duplicates", repo.FindDuplicates)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/duplicates", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, duplicateCandidatesSQL, sql)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), `"score"`))
+	assert.Contains(t, w.Body.String(), `"id":3`)
+}
+
+func TestMergeBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	keptWork, duplicateWork := uint(10), uint(20)
+	gomock.InOrder(
+		expectBook(mockDB, "1", models.Book{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien", Version: 3, WorkID: &keptWork}),
+		expectBook(mockDB, uint(2), models.Book{ID: 2, Title: "Hobbit", Author: "J. R. R. Tolkien", Genre: "Fantasy",
+			ISBN13: "9780547928227", Version: 1, WorkID: &duplicateWork, CoverKeys: map[string]string{"original": "covers/2/ab/original.png"}}),
+	)
+
+	var statements []string
+	var revisions []models.BookRevision
+	var credits []models.BookAuthor
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:credits", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(*[]models.BookAuthor); ok {
+			*dest = []models.BookAuthor{{BookID: 1, AuthorID: 7, Role: models.AuthorRoleAuthor}}
+			if tx.Statement.Vars[0] == uint(2) {
+				*dest = append(*dest, models.BookAuthor{BookID: 2, AuthorID: 8, Role: "translator", Position: 1})
+			}
+		}
+	})
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		tx.RowsAffected = 1
+	}
+	db.Callback().Update().After("gorm:update").Register("test:statement", record)
+	db.Callback().Delete().After("gorm:delete").Register("test:statement", record)
+	db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.BookRevision:
+			revisions = append(revisions, *dest)
+		case *[]models.BookAuthor:
+			credits = append(credits, *dest...)
+		}
+	})
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/merge", repo.MergeBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/1/merge", strings.NewReader(`{"duplicate_id": 2}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"1-4"`, w.Header().Get("ETag"))
+	// The kept book keeps its title and gets the fields it was missing
+	assert.Contains(t, w.Body.String(), `"title":"The Hobbit"`)
+	assert.Contains(t, w.Body.String(), `"genre":"Fantasy"`)
+	assert.Contains(t, w.Body.String(), `"isbn13":"9780547928227"`)
+	assert.Contains(t, w.Body.String(), `"original":"/api/v1/covers/2/ab/original.png"`)
+
+	// The duplicate is trashed before the ISBN moves
+	if assert.Len(t, statements, 7) {
+		assert.True(t, strings.HasPrefix(statements[0], `UPDATE "books" SET "deleted_at"=`), statements[0])
+		assert.Equal(t, `DELETE FROM "books_authors" WHERE book_id = $1`, statements[1])
+		assert.Equal(t, `UPDATE "books" SET "version"=version + 1,"work_id"=$1,"updated_at"=$2 WHERE work_id = $3`, statements[2])
+		assert.Equal(t, `DELETE FROM "works" WHERE "works"."id" = $1`, statements[3])
+		// The cover moves from the duplicate to the kept book
+		assert.Equal(t, `UPDATE "books" SET "cover_keys"=$1,"updated_at"=$2 WHERE "id" = $3`, statements[4])
+		assert.Equal(t, `UPDATE "books" SET "cover_keys"=$1,"updated_at"=$2 WHERE "books"."deleted_at" IS NULL AND "id" = $3`, statements[5])
+	}
+	// Credits the kept book already has are not repeated
+	assert.Equal(t, []models.BookAuthor{{BookID: 1, AuthorID: 8, Role: "translator", Position: 1}}, credits)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, uint(2), revisions[0].BookID)
+		assert.Equal(t, uint(1), *revisions[0].MergedWith)
+		assert.Equal(t, uint(1), revisions[1].BookID)
+		assert.Equal(t, uint(2), *revisions[1].MergedWith)
+		assert.Equal(t, models.RevisionMerge, revisions[1].Action)
+	}
+}
+
+func TestMergeBookIntoItself(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	expectBook(mockDB, "1", models.Book{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/merge", repo.MergeBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/1/merge", strings.NewReader(`{"duplicate_id": 1}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
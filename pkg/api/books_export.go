@@ -0,0 +1,258 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportFlushRows is how many rows are written between flushes of the response
+const exportFlushRows = 100
+
+// Export formats
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+	exportJSON   = "json"
+)
+
+// exportContentTypes maps the export formats to their media type
+var exportContentTypes = map[string]string{
+	exportCSV:    "text/csv; charset=utf-8",
+	exportNDJSON: "application/x-ndjson",
+	exportJSON:   "application/json; charset=utf-8",
+}
+
+// bookExportColumns are the columns of a CSV export, in order
+var bookExportColumns = []struct {
+	name  string
+	value func(models.Book) string
+}{
+	{"id", func(b models.Book) string { return strconv.FormatUint(uint64(b.ID), 10) }},
+	{"title", func(b models.Book) string { return b.Title }},
+	{"author", func(b models.Book) string { return b.Author }},
+	{"genre", func(b models.Book) string { return b.Genre }},
+	{"language", func(b models.Book) string { return b.Language }},
+	{"published_at", func(b models.Book) string { return exportTime(b.PublishedAt) }},
+	{"isbn10", func(b models.Book) string { return b.ISBN10 }},
+	{"isbn13", func(b models.Book) string { return b.ISBN13 }},
+	{"publisher", func(b models.Book) string { return b.Publisher }},
+	{"page_count", func(b models.Book) string { return exportCount(b.PageCount) }},
+	{"description", func(b models.Book) string { return b.Description }},
+	{"edition", func(b models.Book) string { return b.Edition }},
+	{"version", func(b models.Book) string { return strconv.FormatUint(uint64(b.Version), 10) }},
+	{"created_at", func(b models.Book) string { return exportTime(&b.CreatedAt) }},
+	{"updated_at", func(b models.Book) string { return exportTime(&b.UpdatedAt) }},
+}
+
+// exportTime formats t as RFC 3339 in UTC, which the import accepts again
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// exportCount leaves unknown counts empty
+func exportCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// exportEncoder writes books to an export one at a time
+type exportEncoder interface {
+	Begin() error
+	Book(book models.Book) error
+	End() error
+}
+
+func newExportEncoder(format string, w io.Writer) exportEncoder {
+	switch format {
+	case exportNDJSON:
+		return &ndjsonExport{encoder: json.NewEncoder(w)}
+	case exportJSON:
+		return &jsonExport{w: w, encoder: json.NewEncoder(w)}
+	}
+	return &csvExport{writer: csv.NewWriter(w)}
+}
+
+type csvExport struct {
+	writer *csv.Writer
+	record []string
+}
+
+func (e *csvExport) Begin() error {
+	e.record = make([]string, len(bookExportColumns))
+	for i, column := range bookExportColumns {
+		e.record[i] = column.name
+	}
+	return e.writer.Write(e.record)
+}
+
+func (e *csvExport) Book(book models.Book) error {
+	for i, column := range bookExportColumns {
+		e.record[i] = column.value(book)
+	}
+	// The csv writer buffers, so flush it for the response to be flushed too
+	if err := e.writer.Write(e.record); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExport) End() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type ndjsonExport struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExport) Begin() error {
+	return nil
+}
+
+func (e *ndjsonExport) Book(book models.Book) error {
+	return e.encoder.Encode(book)
+}
+
+func (e *ndjsonExport) End() error {
+	return nil
+}
+
+// jsonExport writes a single array with one book per line
+type jsonExport struct {
+	w       io.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+func (e *jsonExport) Begin() error {
+	_, err := io.WriteString(e.w, "[\n")
+	return err
+}
+
+func (e *jsonExport) Book(book models.Book) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.encoder.Encode(book)
+}
+
+func (e *jsonExport) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ExportBooks godoc
+// @Summary Export books
+// @Description Stream all books matching the filters of the list endpoint, in the same order, as CSV, NDJSON or a JSON array. CSV columns are id, title, author, genre, language, published_at, isbn10, isbn13, publisher, page_count, description, edition, version, created_at and updated_at
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param format query string false "Export format (csv, ndjson, json)" default(csv)
+// @Param author query string false "Author, case insensitive"
+// @Param title query string false "Text the title contains, case insensitive"
+// @Param genre query string false "Genre, case insensitive"
+// @Param language query string false "Language, case insensitive"
+// @Param decade query int false "Decade of publication, e.g. 1990"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_after query string false "Updated at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_before query string false "Updated before (RFC 3339 or YYYY-MM-DD)"
+// @Param ids query string false "Comma separated list of book IDs"
+// @Param publisher_id query int false "ID of the publisher books are linked to"
+// @Param series_id query int false "ID of the series books belong to"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending (id, title, author, created_at, updated_at)" default(id)
+// @Success 200 {array} models.Book "Matching books"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/export [get]
+func (r *bookRepository) ExportBooks(c *gin.Context) {
+	format := c.DefaultQuery("format", exportCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected csv, ndjson or json"})
+		return
+	}
+
+	query, err := parseBookQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The query stops when the client goes away
+	db := r.DB.Scopes(query.filter, query.order).WithContext(c.Request.Context()).Model(&models.Book{})
+	rows, err := db.Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="books.%s"`, format))
+	c.Status(http.StatusOK)
+
+	err = writeExport(db, rows, newExportEncoder(format, c.Writer), func(count int64) error {
+		if count%exportFlushRows == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		abortExport(c, err)
+	}
+}
+
+// writeExport encodes every book of rows, calling progress after each one
+func writeExport(db *gorm.DB, rows *sql.Rows, encoder exportEncoder, progress func(count int64) error) error {
+	if err := encoder.Begin(); err != nil {
+		return err
+	}
+	var count int64
+	for rows.Next() {
+		var book models.Book
+		if err := db.ScanRows(rows, &book); err != nil {
+			return err
+		}
+		if err := encoder.Book(book); err != nil {
+			return err
+		}
+		count++
+		if err := progress(count); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return encoder.End()
+}
+
+// abortExport closes the connection without ending the chunked body, so
+// that clients see the export is incomplete instead of a truncated file
+func abortExport(c *gin.Context, err error) {
+	c.Error(err)
+	if conn, _, hijackErr := c.Writer.Hijack(); hijackErr == nil {
+		conn.Close()
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// rowsConnector is a database/sql connector whose queries all return the same rows
+type rowsConnector struct {
+	columns []string
+	rows    [][]driver.Value
+	queries []string
+}
+
+func (c *rowsConnector) Connect(context.Context) (driver.Conn, error) {
+	return &rowsConn{c}, nil
+}
+
+func (c *rowsConnector) Driver() driver.Driver {
+	return nil
+}
+
+type rowsConn struct {
+	connector *rowsConnector
+}
+
+func (c *rowsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *rowsConn) Close() error {
+	return nil
+}
+
+func (c *rowsConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.connector.queries = append(c.connector.queries, query)
+	return &fakeRows{columns: c.connector.columns, rows: c.connector.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// exportTestDB is a database whose book queries return Dune and Emma
+func exportTestDB(t *testing.T) (*gorm.DB, *rowsConnector) {
+	published := time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	connector := &rowsConnector{
+		columns: []string{"id", "title", "author", "genre", "language", "published_at", "isbn10", "isbn13", "publisher", "page_count", "description", "edition", "version", "created_at", "updated_at", "deleted_at"},
+		rows: [][]driver.Value{
+			{int64(2), "Dune", "Frank Herbert", "SF", "en", published, "0441172717", "9780441172719", "Chilton", int64(412), "", "1st", int64(3), created, created, nil},
+			{int64(1), "Emma, a Novel", "Jane Austen", "", "", nil, "", "", "", int64(0), "", "", int64(1), created, created, nil},
+		},
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db, connector
+}
+
+func serveExport(repo *bookRepository, rawQuery string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/export", repo.ExportBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/export?"+rawQuery, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportBooksCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	db, connector := exportTestDB(t)
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(func(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+		return db.Scopes(funcs...)
+	})
+
+	w := serveExport(repo, "author=Jane+Austen&sort=-title")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="books.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, []string{`SELECT * FROM "books" WHERE LOWER(author) = $1 AND "books"."deleted_at" IS NULL ORDER BY title DESC,id`}, connector.queries)
+	assert.Equal(t, "id,title,author,genre,language,published_at,isbn10,isbn13,publisher,page_count,description,edition,version,created_at,updated_at\n"+
+		"2,Dune,Frank Herbert,SF,en,1965-08-01T00:00:00Z,0441172717,9780441172719,Chilton,412,,1st,3,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n"+
+		"1,\"Emma, a Novel\",Jane Austen,,,,,,,,,,1,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n", w.Body.String())
+}
+
+func TestExportBooksJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	db, _ := exportTestDB(t)
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(func(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+		return db.Scopes(funcs...)
+	}).Times(2)
+
+	w := serveExport(repo, "format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var books []models.Book
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &books))
+	assert.Equal(t, []string{"Dune", "Emma, a Novel"}, []string{books[0].Title, books[1].Title})
+
+	w = serveExport(repo, "format=ndjson")
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &books[0]))
+	assert.Equal(t, uint(1), books[0].ID)
+}
+
+func TestExportBooksInvalidFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), nil, &ctx)
+
+	w := serveExport(repo, "format=xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits on the number of values returned per facet
+const (
+	defaultFacetLimit = 10
+	maxFacetLimit     = 50
+)
+
+// bookFacets whitelists the facets books can be counted by, in response order
+var bookFacets = []struct {
+	name       string
+	expression string
+	// condition leaves out books without a value
+	condition string
+}{
+	{"author", "author", "author <> ''"},
+	{"decade", "CAST(EXTRACT(YEAR FROM published_at) AS INTEGER) / 10 * 10", "published_at IS NOT NULL"},
+	{"genre", "genre", "genre <> ''"},
+	{"language", "language", "language <> ''"},
+}
+
+// facetValue is the number of matching books sharing a value
+type facetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// facetResult holds the most frequent values of a facet
+type facetResult struct {
+	Values []facetValue `json:"values"`
+	// HasMore is set when values were left out because of the facet limit
+	HasMore bool `json:"has_more"`
+}
+
+// facetParams holds the requested facets, nil if none were requested
+type facetParams struct {
+	Names []string
+	Limit int
+}
+
+// parseFacetParams reads the facets and facet_limit parameters
+func parseFacetParams(c *gin.Context) (*facetParams, error) {
+	value := c.Query("facets")
+	if value == "" {
+		return nil, nil
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		requested[strings.TrimSpace(name)] = true
+	}
+	params := &facetParams{Limit: defaultFacetLimit}
+	for _, facet := range bookFacets {
+		if requested[facet.name] {
+			params.Names = append(params.Names, facet.name)
+			delete(requested, facet.name)
+		}
+	}
+	for name := range requested {
+		return nil, fmt.Errorf("Invalid facet: %s", name)
+	}
+
+	if value := c.Query("facet_limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, errors.New("Invalid facet_limit format")
+		}
+		params.Limit = min(limit, maxFacetLimit)
+	}
+	return params, nil
+}
+
+// loadFacets counts the books matched by filter per requested facet. The
+// counts are cached under identity, which must identify the filter.
+func (r *bookRepository) loadFacets(params *facetParams, identity string, filter func(*gorm.DB) *gorm.DB) (map[string]facetResult, error) {
+	if identity == "" {
+		identity = "all"
+	}
+	cacheKey := "books_facets_" + identity + "_facets_" + strings.Join(params.Names, ",") + "_limit_" + strconv.Itoa(params.Limit)
+
+	facets := make(map[string]facetResult)
+	cachedFacets, err := r.RedisClient.Get(*r.Ctx, cacheKey).Result()
+	if err == nil {
+		if err := json.Unmarshal([]byte(cachedFacets), &facets); err == nil {
+			return facets, nil
+		}
+	}
+
+	for _, facet := range bookFacets {
+		if !containsString(params.Names, facet.name) {
+			continue
+		}
+		values := []facetValue{}
+		err := r.DB.Model(&models.Book{}).
+			Scopes(filter).
+			Select(facet.expression + " AS value, COUNT(*) AS count").
+			Where(facet.condition).
+			Group(facet.expression).
+			Order("count DESC, value").
+			Limit(params.Limit + 1).
+			Find(&values).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result := facetResult{Values: values}
+		if len(values) > params.Limit {
+			result.Values, result.HasMore = values[:params.Limit], true
+		}
+		facets[facet.name] = result
+	}
+
+	serializedFacets, err := json.Marshal(facets)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.RedisClient.Set(*r.Ctx, cacheKey, serializedFacets, time.Minute).Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
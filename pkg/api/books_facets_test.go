@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestParseFacetParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(rawQuery string) (*facetParams, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/books?"+rawQuery, nil)
+		return parseFacetParams(c)
+	}
+
+	params, err := parse("")
+	assert.NoError(t, err)
+	assert.Nil(t, params)
+
+	params, err = parse("facets=language,author,language&facet_limit=500")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"author", "language"}, params.Names)
+	assert.Equal(t, maxFacetLimit, params.Limit)
+
+	for _, rawQuery := range []string{"facets=password", "facets=genre&facet_limit=0", "facets=genre&facet_limit=x"} {
+		_, err := parse(rawQuery)
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestFindBooksFacets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	cachedPage, _ := json.Marshal(bookPage{})
+	mockCache.EXPECT().Get(ctx, "books_offset_0_limit_10_genre=fantasy").Return(redis.NewStringResult(string(cachedPage), nil))
+	mockCache.EXPECT().Get(ctx, "books_facets_genre=fantasy_facets_author,decade_limit_5").Return(redis.NewStringResult("", redis.Nil))
+	mockCache.EXPECT().Set(ctx, "books_facets_genre=fantasy_facets_author,decade_limit_5", gomock.Any(), time.Minute).Return(redis.NewStatusResult("OK", nil))
+
+	var statements []string
+	mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(func(model interface{}) *gorm.DB {
+		db := dryRunDB(t)
+		db.Callback().Query().After("gorm:query").Register("test:statements", func(tx *gorm.DB) {
+			statements = append(statements, tx.Statement.SQL.String())
+		})
+		return db.Model(model)
+	}).Times(2)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books?genre=Fantasy&facets=decade,author&facet_limit=5", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page bookPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, map[string]facetResult{
+		"author": {Values: []facetValue{}},
+		"decade": {Values: []facetValue{}},
+	}, page.Facets)
+	assert.Equal(t, []string{
+		`SELECT author AS value, COUNT(*) AS count FROM "books" WHERE author <> '' AND LOWER(genre) = $1 AND "books"."deleted_at" IS NULL GROUP BY "author" ORDER BY count DESC, value LIMIT $2`,
+		`SELECT CAST(EXTRACT(YEAR FROM published_at) AS INTEGER) / 10 * 10 AS value, COUNT(*) AS count FROM "books" WHERE published_at IS NOT NULL AND LOWER(genre) = $1 AND "books"."deleted_at" IS NULL GROUP BY CAST(EXTRACT(YEAR FROM published_at) AS INTEGER) / 10 * 10 ORDER BY count DESC, value LIMIT $2`,
+	}, statements)
+}
@@ -0,0 +1,636 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/models"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits of an import
+const (
+	maxImportSize     = 100 << 20
+	maxImportLineSize = 1 << 20
+	importBatchSize   = 500
+)
+
+// ImportReportTTL is how long import reports can be downloaded
+const ImportReportTTL = 24 * time.Hour
+
+// Upload formats
+const (
+	importCSV    = "csv"
+	importNDJSON = "ndjson"
+)
+
+// Outcomes of an imported row
+const (
+	importAccepted = "accepted"
+	importSkipped  = "skipped"
+	importFailed   = "failed"
+)
+
+// bookImportFields are the fields that can be imported, in report order
+var bookImportFields = []string{"title", "author", "genre", "language", "published_at", "isbn10", "isbn13", "publisher", "page_count", "description", "edition"}
+
+// importSummary counts the outcomes of the rows of an import
+type importSummary struct {
+	ID       string `json:"id"`
+	DryRun   bool   `json:"dry_run"`
+	Accepted int    `json:"accepted"`
+	Skipped  int    `json:"skipped"`
+	Failed   int    `json:"failed"`
+	// Report is the URL of the CSV report listing every row
+	Report string `json:"report"`
+}
+
+// importRow is a record of an upload keyed by lower cased column, or the reason it could not be read
+type importRow struct {
+	Line   int
+	Record map[string]interface{}
+	Err    error
+}
+
+// importSource reads the rows of an upload one at a time
+type importSource interface {
+	// Next returns the next row, io.EOF at the end or an error if the upload cannot be read on
+	Next() (importRow, error)
+	// HasColumn reports whether the upload has a column, unknown before reading NDJSON
+	HasColumn(column string) bool
+}
+
+type csvSource struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVSource(r io.Reader) (*csvSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read CSV header: %w", err)
+	}
+	source := &csvSource{reader: reader, header: make([]string, len(header))}
+	for i, column := range header {
+		// Spreadsheets like to start with a byte order mark
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		source.header[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+	return source, nil
+}
+
+func (s *csvSource) Next() (importRow, error) {
+	values, err := s.reader.Read()
+	if err == io.EOF {
+		return importRow{}, err
+	}
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return importRow{Line: parseError.StartLine, Err: parseError.Err}, nil
+	}
+	if err != nil {
+		return importRow{}, err
+	}
+	line, _ := s.reader.FieldPos(0)
+
+	record := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		if i < len(s.header) {
+			record[s.header[i]] = value
+		}
+	}
+	return importRow{Line: line, Record: record}, nil
+}
+
+func (s *csvSource) HasColumn(column string) bool {
+	return containsString(s.header, column)
+}
+
+type ndjsonSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONSource(r io.Reader) *ndjsonSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+	return &ndjsonSource{scanner: scanner}
+}
+
+func (s *ndjsonSource) Next() (importRow, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(line, &object); err != nil {
+			return importRow{Line: s.line, Err: errors.New("invalid JSON")}, nil
+		}
+		record := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			record[strings.ToLower(key)] = value
+		}
+		return importRow{Line: s.line, Record: record}, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return importRow{}, err
+	}
+	return importRow{}, io.EOF
+}
+
+func (s *ndjsonSource) HasColumn(column string) bool {
+	return true
+}
+
+// parseImportMapping reads the column of each field from mapping[field]=column
+// parameters. Fields that are not mapped are read from the column of the same name.
+func parseImportMapping(c *gin.Context) (map[string]string, map[string]bool, error) {
+	mapping := make(map[string]string, len(bookImportFields))
+	for _, field := range bookImportFields {
+		mapping[field] = field
+	}
+	explicit := make(map[string]bool)
+	for field, column := range c.QueryMap("mapping") {
+		if !containsString(bookImportFields, field) {
+			return nil, nil, fmt.Errorf("Invalid mapping field: %s", field)
+		}
+		mapping[field] = strings.ToLower(strings.TrimSpace(column))
+		explicit[field] = true
+	}
+	return mapping, explicit, nil
+}
+
+// importFormat picks the format of the upload from the format parameter,
+// the file name or the content type of the part
+func importFormat(format string, part *multipart.Part) (string, error) {
+	if format == "" {
+		switch strings.ToLower(path.Ext(part.FileName())) {
+		case ".csv":
+			format = importCSV
+		case ".ndjson", ".jsonl":
+			format = importNDJSON
+		}
+	}
+	if format == "" {
+		switch part.Header.Get("Content-Type") {
+		case "text/csv":
+			format = importCSV
+		case "application/x-ndjson", "application/jsonl":
+			format = importNDJSON
+		}
+	}
+	if format != importCSV && format != importNDJSON {
+		return "", errors.New("Unknown format, use format=csv or format=ndjson")
+	}
+	return format, nil
+}
+
+// importBook maps a record to a book and validates it like a POST body
+func importBook(record map[string]interface{}, mapping map[string]string) (models.CreateBook, error) {
+	var input models.CreateBook
+
+	fields := make(map[string]interface{})
+	for _, field := range bookImportFields {
+		value, ok := record[mapping[field]]
+		if text, isText := value.(string); isText {
+			value = strings.TrimSpace(text)
+		}
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		if text, isText := value.(string); isText && field == "published_at" {
+			publishedAt, err := parseTimeParam(text)
+			if err != nil {
+				return input, fmt.Errorf("Invalid published_at: %s", text)
+			}
+			value = publishedAt
+		}
+		// CSV has no numbers
+		if text, isText := value.(string); isText && field == "page_count" {
+			pageCount, err := strconv.Atoi(text)
+			if err != nil {
+				return input, fmt.Errorf("Invalid page_count: %s", text)
+			}
+			value = pageCount
+		}
+		fields[field] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return input, err
+	}
+	return input, decodeBook(data, &input)
+}
+
+// bookKey identifies duplicates: books with the same title and author
+func bookKey(title, author string) string {
+	return strings.ToLower(strings.TrimSpace(title)) + "\x00" + strings.ToLower(strings.TrimSpace(author))
+}
+
+// pendingImport is a row waiting for its batch, so that the report follows the order of the upload
+type pendingImport struct {
+	line  int
+	input models.CreateBook
+	// err is set if the row is invalid
+	err error
+}
+
+// bookImport runs an import, writing the outcome of every row to the report
+type bookImport struct {
+	repo    *bookRepository
+	c       *gin.Context
+	summary importSummary
+	report  *csv.Writer
+	// file holds the report while the import runs
+	file *os.File
+	// seen and seenISBNs hold the lines of the rows accepted so far by book key and ISBN-13
+	seen      map[string]int
+	seenISBNs map[string]int
+	batch     []pendingImport
+}
+
+func newBookImport(repo *bookRepository, c *gin.Context, dryRun bool) (*bookImport, error) {
+	if err := os.MkdirAll(repo.ImportReportDir, 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(repo.ImportReportDir, ".import-*")
+	if err != nil {
+		return nil, err
+	}
+	run := &bookImport{repo: repo, c: c, file: file, seen: make(map[string]int), seenISBNs: make(map[string]int)}
+	run.summary.DryRun = dryRun
+	run.report = csv.NewWriter(file)
+	run.report.Write([]string{"line", "status", "book_id", "title", "author", "message"})
+	return run, nil
+}
+
+// saveReport completes the report and moves it to where ImportReport finds
+// it by the ID of the import
+func (run *bookImport) saveReport() error {
+	run.report.Flush()
+	err := run.report.Error()
+	if closeErr := run.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(run.file.Name(), importReportPath(run.repo.ImportReportDir, run.summary.ID, actor(run.c)))
+	}
+	if err != nil {
+		os.Remove(run.file.Name())
+	}
+	return err
+}
+
+func (run *bookImport) record(line int, status string, bookID uint, input models.CreateBook, message string) {
+	switch status {
+	case importAccepted:
+		run.summary.Accepted++
+	case importSkipped:
+		run.summary.Skipped++
+	case importFailed:
+		run.summary.Failed++
+	}
+	id := ""
+	if bookID != 0 {
+		id = strconv.FormatUint(uint64(bookID), 10)
+	}
+	run.report.Write([]string{strconv.Itoa(line), status, id, input.Title, input.Author, message})
+}
+
+// add checks a row and queues it for the next batch
+func (run *bookImport) add(row importRow, mapping map[string]string) error {
+	pending := pendingImport{line: row.Line, err: row.Err}
+	if pending.err == nil {
+		pending.input, pending.err = importBook(row.Record, mapping)
+	}
+	run.batch = append(run.batch, pending)
+	if len(run.batch) >= importBatchSize {
+		return run.flush()
+	}
+	return nil
+}
+
+// flush skips the duplicates in the batch and creates the other books in one
+// transaction. Each book is saved under a savepoint, so a row that fails is
+// reported with its own error while the others are kept.
+func (run *bookImport) flush() error {
+	if len(run.batch) == 0 {
+		return nil
+	}
+	batch := run.batch
+	run.batch = nil
+
+	var pairs [][]interface{}
+	var isbns []string
+	for _, pending := range batch {
+		if pending.err == nil {
+			pairs = append(pairs, []interface{}{strings.ToLower(strings.TrimSpace(pending.input.Title)), strings.ToLower(strings.TrimSpace(pending.input.Author))})
+			if pending.input.ISBN13 != "" {
+				isbns = append(isbns, pending.input.ISBN13)
+			}
+		}
+	}
+	var existing []models.Book
+	if len(pairs) > 0 {
+		if err := run.repo.DB.Where("(LOWER(title), LOWER(author)) IN ?", pairs).Find(&existing).Error; err != nil {
+			return err
+		}
+	}
+	existingIDs := make(map[string]uint, len(existing))
+	for _, book := range existing {
+		existingIDs[bookKey(book.Title, book.Author)] = book.ID
+	}
+	var sameISBN []models.Book
+	if len(isbns) > 0 {
+		if err := run.repo.DB.Where("isbn13 IN ?", isbns).Find(&sameISBN).Error; err != nil {
+			return err
+		}
+	}
+	isbnIDs := make(map[string]uint, len(sameISBN))
+	for _, book := range sameISBN {
+		isbnIDs[book.ISBN13] = book.ID
+	}
+
+	// Duplicates are skipped, also within the upload
+	accepted := make(map[int]bool)
+	books := make(map[int]*models.Book)
+	for i, pending := range batch {
+		if pending.err != nil {
+			continue
+		}
+		key, isbn13 := bookKey(pending.input.Title, pending.input.Author), pending.input.ISBN13
+		if id, ok := existingIDs[key]; ok {
+			batch[i].err = skipped{id, "Duplicate of an existing book"}
+		} else if id, ok := isbnIDs[isbn13]; ok && isbn13 != "" {
+			batch[i].err = skipped{id, "ISBN-13 of an existing book"}
+		} else if line, ok := run.seen[key]; ok {
+			batch[i].err = skipped{0, "Duplicate of line " + strconv.Itoa(line)}
+		} else if line, ok := run.seenISBNs[isbn13]; ok && isbn13 != "" {
+			batch[i].err = skipped{0, "ISBN-13 of line " + strconv.Itoa(line)}
+		} else {
+			run.seen[key] = pending.line
+			if isbn13 != "" {
+				run.seenISBNs[isbn13] = pending.line
+			}
+			accepted[i] = true
+			book := newBook(pending.input)
+			books[i] = &book
+		}
+	}
+
+	var err error
+	rejected := make(map[int]error)
+	if !run.summary.DryRun && len(books) > 0 {
+		err = run.repo.DB.Transaction(func(tx *gorm.DB) error {
+			for i := range batch {
+				book, ok := books[i]
+				if !ok {
+					continue
+				}
+				if err := tx.SavePoint("import_row").Error; err != nil {
+					return err
+				}
+				if err := insertBook(tx, book, newRevision(run.c, models.RevisionCreate)); err != nil {
+					if err := tx.RollbackTo("import_row").Error; err != nil {
+						return err
+					}
+					rejected[i] = err
+				}
+			}
+			return nil
+		})
+	}
+
+	for i, pending := range batch {
+		var skip skipped
+		switch {
+		case accepted[i] && err != nil:
+			run.record(pending.line, importFailed, 0, pending.input, "Failed to save book")
+		case accepted[i] && rejected[i] != nil:
+			run.forget(pending)
+			run.record(pending.line, importFailed, 0, pending.input, importError(rejected[i]))
+		case accepted[i]:
+			run.record(pending.line, importAccepted, books[i].ID, pending.input, "")
+		case errors.As(pending.err, &skip):
+			run.record(pending.line, importSkipped, skip.bookID, pending.input, skip.reason)
+		default:
+			run.record(pending.line, importFailed, 0, pending.input, pending.err.Error())
+		}
+	}
+	return nil
+}
+
+// forget lets later rows with the title and author or the ISBN-13 of a row
+// that failed to save be imported
+func (run *bookImport) forget(pending pendingImport) {
+	if key := bookKey(pending.input.Title, pending.input.Author); run.seen[key] == pending.line {
+		delete(run.seen, key)
+	}
+	if isbn13 := pending.input.ISBN13; isbn13 != "" && run.seenISBNs[isbn13] == pending.line {
+		delete(run.seenISBNs, isbn13)
+	}
+}
+
+// importError is the report message of a row that failed to save
+func importError(err error) string {
+	if _, problems := rejectedBook(err); problems != nil {
+		return problems.Error()
+	}
+	return "Failed to save book"
+}
+
+// skipped is the reason a valid row was not imported
+type skipped struct {
+	bookID uint
+	reason string
+}
+
+func (s skipped) Error() string {
+	return s.reason
+}
+
+// ImportBooks godoc
+// @Summary Import books from CSV or NDJSON
+// @Description Import books from a multipart upload in the file field. CSV files need a header row, NDJSON files hold one object per line. Columns are matched to the fields title, author, genre, language, published_at, isbn10, isbn13, publisher, page_count, description and edition by name, case insensitive, unless mapped otherwise. Books with the title and author or the ISBN-13 of an existing book or of an earlier row are skipped, rows that fail to save are reported with their own error. The upload is processed as it streams in
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or NDJSON file"
+// @Param format query string false "csv or ndjson, by default taken from the file name"
+// @Param dry_run query bool false "Check the rows without creating books"
+// @Param mapping[title] query string false "Column holding the title, likewise for the other fields"
+// @Success 200 {object} importSummary "Counts of accepted, skipped and failed rows and the report URL"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 413 {string} string "Upload too large"
+// @Router /books/import [post]
+func (r *bookRepository) ImportBooks(c *gin.Context) {
+	mapping, explicit, err := parseImportMapping(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the file as multipart/form-data"})
+		return
+	}
+	var part *multipart.Part
+	for {
+		if part, err = reader.NextPart(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+	}
+
+	format, err := importFormat(c.Query("format"), part)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var source importSource
+	if format == importCSV {
+		if source, err = newCSVSource(part); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		source = newNDJSONSource(part)
+	}
+	for _, field := range bookImportFields {
+		if (explicit[field] || field == "title" || field == "author") && !source.HasColumn(mapping[field]) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Missing column %q for %s", mapping[field], field)})
+			return
+		}
+	}
+
+	run, err := newBookImport(r, c, c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import report"})
+		return
+	}
+	var readErr, storeErr error
+	for readErr == nil && storeErr == nil {
+		var row importRow
+		if row, readErr = source.Next(); readErr == nil {
+			storeErr = run.add(row, mapping)
+		}
+	}
+	if readErr == io.EOF {
+		readErr, storeErr = nil, run.flush()
+	}
+	if run.summary.Accepted > 0 && !run.summary.DryRun {
+		r.invalidateBookCache()
+		r.Suggestions.Invalidate()
+	}
+
+	// Rows imported before a failure stay imported, so the report is kept either way
+	run.summary.ID = auth.RandomHex(16)
+	if err := run.saveReport(); err != nil {
+		log.Printf("Failed to save import report %s: %v", run.summary.ID, err)
+	} else {
+		run.summary.Report = c.Request.URL.Path + "/" + run.summary.ID + "/report"
+	}
+
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case storeErr != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import books", "data": run.summary})
+	case errors.As(readErr, &maxBytesError):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload too large", "data": run.summary})
+	case readErr != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload: " + readErr.Error(), "data": run.summary})
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": run.summary})
+	}
+}
+
+// ImportReport godoc
+// @Summary Download an import report
+// @Description Download the CSV report of an import, listing the line, status, book ID, title, author and message of every row. Reports are kept for 24 hours. Only the caller who ran the import can download it
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce text/csv
+// @Param id path string true "Import ID"
+// @Success 200 {string} string "CSV report"
+// @Failure 404 {string} string "report not found"
+// @Router /books/import/{id}/report [get]
+func (r *bookRepository) ImportReport(c *gin.Context) {
+	id := c.Param("id")
+	if !validImportID(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	// Reports of other callers are not found, like their exports
+	file, err := os.Open(importReportPath(r.ImportReportDir, id, actor(c)))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || time.Since(info.ModTime()) > ImportReportTTL {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="import-`+id+`.csv"`)
+	c.DataFromReader(http.StatusOK, info.Size(), "text/csv; charset=utf-8", file, nil)
+}
+
+// ImportReportDir reads the directory of import reports from IMPORT_REPORT_DIR
+func ImportReportDir() string {
+	if dir := os.Getenv("IMPORT_REPORT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "book-import-reports")
+}
+
+// importReportPath is the file of the report of the import with ID id run
+// by owner. The name holds a hash of the owner, so only they can find it.
+func importReportPath(dir, id, owner string) string {
+	return filepath.Join(dir, id+"-"+auth.HashToken(owner)[:16]+".csv")
+}
+
+// validImportID reports whether id has the form of the IDs given to imports,
+// which keeps it from naming files outside of the report directory
+func validImportID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
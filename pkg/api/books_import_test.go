@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestImportBook(t *testing.T) {
+	mapping := map[string]string{"title": "book title", "author": "author", "published_at": "published_at"}
+
+	input, err := importBook(map[string]interface{}{"book title": " Dune ", "author": "Frank Herbert", "published_at": "1965-08-01"}, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "Dune", input.Title)
+	assert.Equal(t, time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC), *input.PublishedAt)
+
+	mapping["page_count"] = "pages"
+	input, err = importBook(map[string]interface{}{"book title": "Dune", "author": "Frank Herbert", "pages": "412"}, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, 412, input.PageCount)
+
+	_, err = importBook(map[string]interface{}{"book title": "Dune", "author": ""}, mapping)
+	assert.Error(t, err)
+
+	_, err = importBook(map[string]interface{}{"book title": "Dune", "author": "Frank Herbert", "published_at": "August 1965"}, mapping)
+	assert.EqualError(t, err, "Invalid published_at: August 1965")
+}
+
+func serveImport(t *testing.T, repo *bookRepository, rawQuery, filename, content string) (int, importSummary) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	writer.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/import", func(c *gin.Context) { c.Set("username", "jane") }, repo.ImportBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/import?"+rawQuery, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r.ServeHTTP(w, req)
+
+	var response struct {
+		Data importSummary `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Data
+}
+
+// importReport reads the report of an import
+func importReport(t *testing.T, repo *bookRepository, id string) string {
+	report, err := os.ReadFile(importReportPath(repo.ImportReportDir, id, "jane"))
+	if err != nil {
+		t.Fatalf("Failed to read import report: %v", err)
+	}
+	return string(report)
+}
+
+func TestImportBooksCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+	repo.ImportReportDir = t.TempDir()
+
+	// Dune already exists
+	existing := dryRunDB(t)
+	existing.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		*tx.Statement.Dest.(*[]models.Book) = []models.Book{{ID: 9, Title: "Dune", Author: "Frank Herbert"}}
+	})
+	mockDB.EXPECT().Where("(LOWER(title), LOWER(author)) IN ?", gomock.Any()).Return(&database.GormDatabase{DB: existing})
+
+	db := dryRunDB(t)
+	var created []string
+	db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		if book, ok := tx.Statement.Dest.(*models.Book); ok {
+			book.ID = uint(len(created) + 1)
+			created = append(created, book.Title)
+		}
+	})
+	expectTransaction(mockDB, db)
+
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	status, summary := serveImport(t, repo, "mapping[title]=Book+Title&mapping[author]=Writer", "catalog.csv",
+		"\ufeffBook Title,Writer,Genre\n"+
+			"Emma,Jane Austen,Classic\n"+
+			"DUNE,frank herbert,SF\n"+
+			"\"Persuasion\",Jane Austen\n"+
+			"emma,Jane Austen,\n"+
+			"Nameless,,\n")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, importSummary{ID: summary.ID, Accepted: 2, Skipped: 2, Failed: 1, Report: "/books/import/" + summary.ID + "/report"}, summary)
+	assert.Equal(t, []string{"Emma", "Persuasion"}, created)
+	assert.Equal(t, "line,status,book_id,title,author,message\n"+
+		"2,accepted,1,Emma,Jane Austen,\n"+
+		"3,skipped,9,DUNE,frank herbert,Duplicate of an existing book\n"+
+		"4,accepted,2,Persuasion,Jane Austen,\n"+
+		"5,skipped,,emma,Jane Austen,Duplicate of line 2\n"+
+		"6,failed,,Nameless,,author: is required\n", importReport(t, repo, summary.ID))
+}
+
+func TestImportBooksRowFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+	repo.ImportReportDir = t.TempDir()
+
+	mockDB.EXPECT().Where(gomock.Any(), gomock.Any()).Return(&database.GormDatabase{DB: dryRunDB(t)}).Times(2)
+
+	// Emma lost a race for its ISBN-13, the books after it are still saved
+	db := dryRunDB(t)
+	var statements []string
+	db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		if book, ok := tx.Statement.Dest.(*models.Book); ok {
+			if book.Title == "Emma" {
+				tx.AddError(gorm.ErrDuplicatedKey)
+				return
+			}
+			book.ID = 2
+		}
+	})
+	db.Callback().Raw().After("gorm:raw").Register("test:raw", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	status, summary := serveImport(t, repo, "", "catalog.csv",
+		"title,author,isbn13\n"+
+			"Emma,Jane Austen,9780141439587\n"+
+			"Persuasion,Jane Austen,\n")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, summary.Accepted)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, []string{"SAVEPOINT import_row", "ROLLBACK TO SAVEPOINT import_row", "SAVEPOINT import_row"}, statements)
+	assert.Equal(t, "line,status,book_id,title,author,message\n"+
+		"2,failed,,Emma,Jane Austen,isbn13: is already used by another book\n"+
+		"3,accepted,2,Persuasion,Jane Austen,\n", importReport(t, repo, summary.ID))
+}
+
+func TestImportBooksDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+	repo.ImportReportDir = t.TempDir()
+
+	// Nothing is written and the caches stay valid
+	mockDB.EXPECT().Where(gomock.Any(), gomock.Any()).Return(&database.GormDatabase{DB: dryRunDB(t)})
+
+	status, summary := serveImport(t, repo, "dry_run=true", "catalog.ndjson",
+		`{"Title": "Emma", "Author": "Jane Austen", "published_at": "1815-12-23"}`+"\n\n"+
+			`{"title": "Dune"`+"\n")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, summary.DryRun)
+	assert.Equal(t, 1, summary.Accepted)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Contains(t, importReport(t, repo, summary.ID), "1,accepted,,Emma,Jane Austen,\n")
+}
+
+func TestImportBooksMissingColumn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), nil, &ctx)
+	repo.ImportReportDir = t.TempDir()
+
+	status, _ := serveImport(t, repo, "", "catalog.csv", "name,author\nEmma,Jane Austen\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestImportReport(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBookRepository(nil, nil, &ctx)
+	repo.ImportReportDir = t.TempDir()
+	id := auth.RandomHex(16)
+	os.WriteFile(importReportPath(repo.ImportReportDir, id, "jane"), []byte("line,status\n"), 0o600)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/import/:id/report", func(c *gin.Context) { c.Set("username", c.GetHeader("X-User")) }, repo.ImportReport)
+	get := func(id, username string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/books/import/"+id+"/report", nil)
+		req.Header.Set("X-User", username)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(id, "jane")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "line,status\n", w.Body.String())
+
+	// Only the caller who ran the import finds its report
+	assert.Equal(t, http.StatusNotFound, get(id, "mallory").Code)
+	assert.Equal(t, http.StatusNotFound, get(auth.RandomHex(16), "jane").Code)
+	assert.Equal(t, http.StatusNotFound, get("..%2F..%2Fetc%2Fpasswd", "jane").Code)
+
+	expired := time.Now().Add(-ImportReportTTL - time.Minute)
+	os.Chtimes(importReportPath(repo.ImportReportDir, id, "jane"), expired, expired)
+	assert.Equal(t, http.StatusNotFound, get(id, "jane").Code)
+}
@@ -34,6 +34,42 @@ func (m *MockBookRepository) EXPECT() *MockBookRepositoryMockRecorder {
 	return m.recorder
 }
 
+// BookAuthors mocks base method.
+func (m *MockBookRepository) BookAuthors(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BookAuthors", c)
+}
+
+// BookAuthors indicates an expected call of BookAuthors.
+func (mr *MockBookRepositoryMockRecorder) BookAuthors(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BookAuthors", reflect.TypeOf((*MockBookRepository)(nil).BookAuthors), c)
+}
+
+// BookHistory mocks base method.
+func (m *MockBookRepository) BookHistory(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BookHistory", c)
+}
+
+// BookHistory indicates an expected call of BookHistory.
+func (mr *MockBookRepositoryMockRecorder) BookHistory(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BookHistory", reflect.TypeOf((*MockBookRepository)(nil).BookHistory), c)
+}
+
+// BulkBooks mocks base method.
+func (m *MockBookRepository) BulkBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BulkBooks", c)
+}
+
+// BulkBooks indicates an expected call of BulkBooks.
+func (mr *MockBookRepositoryMockRecorder) BulkBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkBooks", reflect.TypeOf((*MockBookRepository)(nil).BulkBooks), c)
+}
+
 // CreateBook mocks base method.
 func (m *MockBookRepository) CreateBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -58,6 +94,30 @@ func (mr *MockBookRepositoryMockRecorder) DeleteBook(c interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockBookRepository)(nil).DeleteBook), c)
 }
 
+// DeleteCover mocks base method.
+func (m *MockBookRepository) DeleteCover(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteCover", c)
+}
+
+// DeleteCover indicates an expected call of DeleteCover.
+func (mr *MockBookRepositoryMockRecorder) DeleteCover(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCover", reflect.TypeOf((*MockBookRepository)(nil).DeleteCover), c)
+}
+
+// ExportBooks mocks base method.
+func (m *MockBookRepository) ExportBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExportBooks", c)
+}
+
+// ExportBooks indicates an expected call of ExportBooks.
+func (mr *MockBookRepositoryMockRecorder) ExportBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportBooks", reflect.TypeOf((*MockBookRepository)(nil).ExportBooks), c)
+}
+
 // FindBook mocks base method.
 func (m *MockBookRepository) FindBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -82,6 +142,42 @@ func (mr *MockBookRepositoryMockRecorder) FindBooks(c interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBooks", reflect.TypeOf((*MockBookRepository)(nil).FindBooks), c)
 }
 
+// FindCover mocks base method.
+func (m *MockBookRepository) FindCover(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindCover", c)
+}
+
+// FindCover indicates an expected call of FindCover.
+func (mr *MockBookRepositoryMockRecorder) FindCover(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCover", reflect.TypeOf((*MockBookRepository)(nil).FindCover), c)
+}
+
+// FindDuplicates mocks base method.
+func (m *MockBookRepository) FindDuplicates(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindDuplicates", c)
+}
+
+// FindDuplicates indicates an expected call of FindDuplicates.
+func (mr *MockBookRepositoryMockRecorder) FindDuplicates(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicates", reflect.TypeOf((*MockBookRepository)(nil).FindDuplicates), c)
+}
+
+// FindWork mocks base method.
+func (m *MockBookRepository) FindWork(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindWork", c)
+}
+
+// FindWork indicates an expected call of FindWork.
+func (mr *MockBookRepositoryMockRecorder) FindWork(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindWork", reflect.TypeOf((*MockBookRepository)(nil).FindWork), c)
+}
+
 // Healthcheck mocks base method.
 func (m *MockBookRepository) Healthcheck(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -94,6 +190,162 @@ func (mr *MockBookRepositoryMockRecorder) Healthcheck(c interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthcheck", reflect.TypeOf((*MockBookRepository)(nil).Healthcheck), c)
 }
 
+// ImportBooks mocks base method.
+func (m *MockBookRepository) ImportBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ImportBooks", c)
+}
+
+// ImportBooks indicates an expected call of ImportBooks.
+func (mr *MockBookRepositoryMockRecorder) ImportBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportBooks", reflect.TypeOf((*MockBookRepository)(nil).ImportBooks), c)
+}
+
+// ImportReport mocks base method.
+func (m *MockBookRepository) ImportReport(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ImportReport", c)
+}
+
+// ImportReport indicates an expected call of ImportReport.
+func (mr *MockBookRepositoryMockRecorder) ImportReport(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportReport", reflect.TypeOf((*MockBookRepository)(nil).ImportReport), c)
+}
+
+// MergeBooks mocks base method.
+func (m *MockBookRepository) MergeBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MergeBooks", c)
+}
+
+// MergeBooks indicates an expected call of MergeBooks.
+func (mr *MockBookRepositoryMockRecorder) MergeBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeBooks", reflect.TypeOf((*MockBookRepository)(nil).MergeBooks), c)
+}
+
+// MergeWorks mocks base method.
+func (m *MockBookRepository) MergeWorks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MergeWorks", c)
+}
+
+// MergeWorks indicates an expected call of MergeWorks.
+func (mr *MockBookRepositoryMockRecorder) MergeWorks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeWorks", reflect.TypeOf((*MockBookRepository)(nil).MergeWorks), c)
+}
+
+// PatchBook mocks base method.
+func (m *MockBookRepository) PatchBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PatchBook", c)
+}
+
+// PatchBook indicates an expected call of PatchBook.
+func (mr *MockBookRepositoryMockRecorder) PatchBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchBook", reflect.TypeOf((*MockBookRepository)(nil).PatchBook), c)
+}
+
+// PurgeBook mocks base method.
+func (m *MockBookRepository) PurgeBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PurgeBook", c)
+}
+
+// PurgeBook indicates an expected call of PurgeBook.
+func (mr *MockBookRepositoryMockRecorder) PurgeBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeBook", reflect.TypeOf((*MockBookRepository)(nil).PurgeBook), c)
+}
+
+// RestoreBook mocks base method.
+func (m *MockBookRepository) RestoreBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RestoreBook", c)
+}
+
+// RestoreBook indicates an expected call of RestoreBook.
+func (mr *MockBookRepositoryMockRecorder) RestoreBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreBook", reflect.TypeOf((*MockBookRepository)(nil).RestoreBook), c)
+}
+
+// RevertBook mocks base method.
+func (m *MockBookRepository) RevertBook(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RevertBook", c)
+}
+
+// RevertBook indicates an expected call of RevertBook.
+func (mr *MockBookRepositoryMockRecorder) RevertBook(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertBook", reflect.TypeOf((*MockBookRepository)(nil).RevertBook), c)
+}
+
+// SearchBooks mocks base method.
+func (m *MockBookRepository) SearchBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SearchBooks", c)
+}
+
+// SearchBooks indicates an expected call of SearchBooks.
+func (mr *MockBookRepositoryMockRecorder) SearchBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchBooks", reflect.TypeOf((*MockBookRepository)(nil).SearchBooks), c)
+}
+
+// SetBookAuthors mocks base method.
+func (m *MockBookRepository) SetBookAuthors(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBookAuthors", c)
+}
+
+// SetBookAuthors indicates an expected call of SetBookAuthors.
+func (mr *MockBookRepositoryMockRecorder) SetBookAuthors(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookAuthors", reflect.TypeOf((*MockBookRepository)(nil).SetBookAuthors), c)
+}
+
+// SplitWork mocks base method.
+func (m *MockBookRepository) SplitWork(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SplitWork", c)
+}
+
+// SplitWork indicates an expected call of SplitWork.
+func (mr *MockBookRepositoryMockRecorder) SplitWork(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SplitWork", reflect.TypeOf((*MockBookRepository)(nil).SplitWork), c)
+}
+
+// SuggestBooks mocks base method.
+func (m *MockBookRepository) SuggestBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SuggestBooks", c)
+}
+
+// SuggestBooks indicates an expected call of SuggestBooks.
+func (mr *MockBookRepositoryMockRecorder) SuggestBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestBooks", reflect.TypeOf((*MockBookRepository)(nil).SuggestBooks), c)
+}
+
+// TrashedBooks mocks base method.
+func (m *MockBookRepository) TrashedBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TrashedBooks", c)
+}
+
+// TrashedBooks indicates an expected call of TrashedBooks.
+func (mr *MockBookRepositoryMockRecorder) TrashedBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashedBooks", reflect.TypeOf((*MockBookRepository)(nil).TrashedBooks), c)
+}
+
 // UpdateBook mocks base method.
 func (m *MockBookRepository) UpdateBook(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -105,3 +357,39 @@ func (mr *MockBookRepositoryMockRecorder) UpdateBook(c interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBook", reflect.TypeOf((*MockBookRepository)(nil).UpdateBook), c)
 }
+
+// UpdateWork mocks base method.
+func (m *MockBookRepository) UpdateWork(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateWork", c)
+}
+
+// UpdateWork indicates an expected call of UpdateWork.
+func (mr *MockBookRepositoryMockRecorder) UpdateWork(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWork", reflect.TypeOf((*MockBookRepository)(nil).UpdateWork), c)
+}
+
+// UploadCover mocks base method.
+func (m *MockBookRepository) UploadCover(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UploadCover", c)
+}
+
+// UploadCover indicates an expected call of UploadCover.
+func (mr *MockBookRepositoryMockRecorder) UploadCover(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadCover", reflect.TypeOf((*MockBookRepository)(nil).UploadCover), c)
+}
+
+// WorkEditions mocks base method.
+func (m *MockBookRepository) WorkEditions(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "WorkEditions", c)
+}
+
+// WorkEditions indicates an expected call of WorkEditions.
+func (mr *MockBookRepositoryMockRecorder) WorkEditions(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WorkEditions", reflect.TypeOf((*MockBookRepository)(nil).WorkEditions), c)
+}
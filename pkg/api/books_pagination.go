@@ -0,0 +1,281 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page size limits of book listings
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// bookPage is a page of a book listing as returned and cached by FindBooks
+type bookPage struct {
+	Data       []models.Book `json:"data"`
+	HasMore    bool          `json:"has_more"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+	Total      *int64        `json:"total,omitempty"`
+	// Facets are added after the page is read from or written to the cache
+	Facets map[string]facetResult `json:"facets,omitempty"`
+}
+
+// pageParams holds the validated pagination parameters of a book listing.
+// Cursor mode is selected by the presence of the cursor parameter, an empty
+// cursor requesting the first page.
+type pageParams struct {
+	Offset     int
+	Limit      int
+	CursorMode bool
+	Cursor     *bookCursor
+	Count      bool
+}
+
+// bookCursor is the decoded form of an opaque pagination cursor. It holds the
+// sort values of the book the page starts after (or before, going back).
+type bookCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	Prev   bool     `json:"p,omitempty"`
+}
+
+// parsePageParams reads the pagination parameters of a request
+func parsePageParams(c *gin.Context, query *bookQuery) (*pageParams, error) {
+	params := &pageParams{Count: c.Query("count") == "true"}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		return nil, err
+	}
+	params.Limit = limit
+
+	if value, ok := c.GetQuery("cursor"); ok {
+		params.CursorMode = true
+		if value != "" {
+			cursor, err := decodeCursor(value, query)
+			if err != nil {
+				return nil, err
+			}
+			params.Cursor = cursor
+		}
+		return params, nil
+	}
+
+	offset, err := parseOffset(c)
+	if err != nil {
+		return nil, err
+	}
+	params.Offset = offset
+	return params, nil
+}
+
+// parseLimit reads the page size, capped at maxPageLimit
+func parseLimit(c *gin.Context) (int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil {
+		return 0, errors.New("Invalid limit format")
+	}
+	if limit < 1 {
+		return 0, errors.New("Limit must be positive")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, nil
+}
+
+// parseOffset reads the number of rows to skip
+func parseOffset(c *gin.Context) (int, error) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		return 0, errors.New("Invalid offset format")
+	}
+	if offset < 0 {
+		return 0, errors.New("Offset must not be negative")
+	}
+	return offset, nil
+}
+
+// cacheKey identifies the page in the cache
+func (p *pageParams) cacheKey(query *bookQuery) string {
+	var key string
+	if p.CursorMode {
+		key = "books_cursor_"
+		if p.Cursor != nil {
+			key += p.Cursor.encode()
+		}
+		key += "_limit_" + strconv.Itoa(p.Limit)
+	} else {
+		key = "books_offset_" + strconv.Itoa(p.Offset) + "_limit_" + strconv.Itoa(p.Limit)
+	}
+	if normalized := query.normalized(); normalized != "" {
+		key += "_" + normalized
+	}
+	if p.Count {
+		key += "_count"
+	}
+	return key
+}
+
+func (cursor *bookCursor) encode() string {
+	serialized, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(serialized)
+}
+
+// decodeCursor validates a cursor against the sort of the query it is used with
+func decodeCursor(raw string, query *bookQuery) (*bookCursor, error) {
+	serialized, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor bookCursor
+	if err := json.Unmarshal(serialized, &cursor); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != query.sortParam() || len(cursor.Values) != len(query.Sort) {
+		return nil, errors.New("Cursor does not match the sort of the request")
+	}
+	for i, field := range query.Sort {
+		if _, err := parseSortValue(field.Column, cursor.Values[i]); err != nil {
+			return nil, errInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
+
+// newCursor points at book in the sort order of query
+func newCursor(query *bookQuery, book models.Book, prev bool) string {
+	cursor := bookCursor{Sort: query.sortParam(), Prev: prev}
+	for _, field := range query.Sort {
+		cursor.Values = append(cursor.Values, sortValue(book, field.Column))
+	}
+	return cursor.encode()
+}
+
+// sortValue formats the value of a sort column of book
+func sortValue(book models.Book, column string) string {
+	switch column {
+	case "title":
+		return book.Title
+	case "author":
+		return book.Author
+	case "created_at":
+		return book.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		return book.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatUint(uint64(book.ID), 10)
+	}
+}
+
+// parseSortValue is the inverse of sortValue
+func parseSortValue(column, value string) (interface{}, error) {
+	switch column {
+	case "title", "author":
+		return value, nil
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return strconv.ParseUint(value, 10, 64)
+	}
+}
+
+// keyset returns a gorm scope selecting the books after the cursor in the
+// direction of the cursor. For a sort a, -b, id this is
+// (a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?).
+func (q *bookQuery) keyset(cursor *bookCursor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		values := make([]interface{}, len(q.Sort))
+		for i, field := range q.Sort {
+			values[i], _ = parseSortValue(field.Column, cursor.Values[i])
+		}
+
+		var clauses []string
+		var args []interface{}
+		for i, field := range q.Sort {
+			var parts []string
+			for j := 0; j < i; j++ {
+				parts = append(parts, q.Sort[j].Column+" = ?")
+				args = append(args, values[j])
+			}
+			operator := ">"
+			if field.Desc != cursor.Prev {
+				operator = "<"
+			}
+			parts = append(parts, field.Column+" "+operator+" ?")
+			args = append(args, values[i])
+			clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		}
+		return db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+}
+
+// reverseOrder is a gorm scope applying the inverted sort of the query, used
+// to walk backwards from a prev cursor
+func (q *bookQuery) reverseOrder(db *gorm.DB) *gorm.DB {
+	for _, field := range q.Sort {
+		if field.Desc {
+			db = db.Order(field.Column)
+		} else {
+			db = db.Order(field.Column + " DESC")
+		}
+	}
+	return db
+}
+
+// linkHeader formats RFC 8288 links to the first, previous, next and, when
+// the total is known, last pages
+func linkHeader(requestURL *url.URL, params *pageParams, page *bookPage) string {
+	link := func(rel string, set map[string]string) string {
+		query := requestURL.Query()
+		for key, value := range set {
+			query.Set(key, value)
+		}
+		target := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+	}
+
+	limit := strconv.Itoa(params.Limit)
+	var links []string
+	if params.CursorMode {
+		links = append(links, link("first", map[string]string{"cursor": "", "limit": limit}))
+		if page.PrevCursor != "" {
+			links = append(links, link("prev", map[string]string{"cursor": page.PrevCursor, "limit": limit}))
+		}
+		if page.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor, "limit": limit}))
+		}
+		return strings.Join(links, ", ")
+	}
+
+	links = append(links, link("first", map[string]string{"offset": "0", "limit": limit}))
+	if params.Offset > 0 {
+		prev := params.Offset - params.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", map[string]string{"offset": strconv.Itoa(prev), "limit": limit}))
+	}
+	if page.HasMore {
+		links = append(links, link("next", map[string]string{"offset": strconv.Itoa(params.Offset + params.Limit), "limit": limit}))
+	}
+	if page.Total != nil && *page.Total > 0 {
+		last := int((*page.Total - 1) / int64(params.Limit) * int64(params.Limit))
+		links = append(links, link("last", map[string]string{"offset": strconv.Itoa(last), "limit": limit}))
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func parseTestPage(t *testing.T, rawQuery string) (*bookQuery, *pageParams, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/books?"+rawQuery, nil)
+	query, err := parseBookQuery(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, err := parsePageParams(c, query)
+	return query, params, err
+}
+
+func TestParsePageParams(t *testing.T) {
+	_, params, err := parseTestPage(t, "limit=1000&offset=20")
+	assert.NoError(t, err)
+	assert.Equal(t, maxPageLimit, params.Limit)
+	assert.Equal(t, 20, params.Offset)
+	assert.False(t, params.CursorMode)
+
+	_, params, err = parseTestPage(t, "cursor=&count=true")
+	assert.NoError(t, err)
+	assert.True(t, params.CursorMode)
+	assert.Nil(t, params.Cursor)
+	assert.True(t, params.Count)
+
+	for _, rawQuery := range []string{"limit=-1", "limit=0", "offset=-10", "cursor=not-a-cursor"} {
+		_, _, err := parseTestPage(t, rawQuery)
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestCursorMustMatchSort(t *testing.T) {
+	query, _, err := parseTestPage(t, "sort=title")
+	assert.NoError(t, err)
+	cursor := newCursor(query, models.Book{ID: 7, Title: "Dune"}, false)
+
+	_, params, err := parseTestPage(t, "sort=title&cursor="+cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Dune", "7"}, params.Cursor.Values)
+
+	_, _, err = parseTestPage(t, "sort=-title&cursor="+cursor)
+	assert.Error(t, err)
+}
+
+func TestBookQueryKeyset(t *testing.T) {
+	query, _, err := parseTestPage(t, "sort=title,-created_at")
+	assert.NoError(t, err)
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	book := models.Book{ID: 3, Title: "Dune", CreatedAt: createdAt}
+
+	for _, test := range []struct {
+		prev bool
+		sql  string
+	}{
+		{false, `SELECT * FROM "books" WHERE (((title > $1) OR (title = $2 AND created_at < $3) OR (title = $4 AND created_at = $5 AND id > $6))) AND "books"."deleted_at" IS NULL ORDER BY title,created_at DESC,id`},
+		{true, `SELECT * FROM "books" WHERE (((title < $1) OR (title = $2 AND created_at > $3) OR (title = $4 AND created_at = $5 AND id < $6))) AND "books"."deleted_at" IS NULL ORDER BY title DESC,created_at,id DESC`},
+	} {
+		cursor, err := decodeCursor(newCursor(query, book, test.prev), query)
+		assert.NoError(t, err)
+
+		order := query.order
+		if test.prev {
+			order = query.reverseOrder
+		}
+		var books []models.Book
+		stmt := dryRunDB(t).Scopes(query.keyset(cursor), order).Find(&books).Statement
+
+		assert.Equal(t, test.sql, stmt.SQL.String())
+		assert.Equal(t, createdAt, stmt.Vars[2])
+		assert.Equal(t, uint64(3), stmt.Vars[5])
+	}
+}
+
+func TestFindBooksCursorPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	// Return one book more than the limit so a next page is detected
+	db := dryRunDBWithRows(t, []models.Book{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}, {ID: 3, Title: "C"}})
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(func(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+		return db.Scopes(funcs...)
+	})
+	mockCache.EXPECT().Get(ctx, "books_cursor__limit_2").Return(redis.NewStringResult("", redis.Nil))
+	mockCache.EXPECT().Set(ctx, "books_cursor__limit_2", gomock.Any(), time.Minute).Return(redis.NewStatusResult("OK", nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books?cursor=&limit=2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page bookPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Data, 2)
+	assert.True(t, page.HasMore)
+	assert.Empty(t, page.PrevCursor)
+
+	query, _, _ := parseTestPage(t, "")
+	next, err := decodeCursor(page.NextCursor, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, next.Values)
+
+	assert.Equal(t,
+		`</books?cursor=&limit=2>; rel="first", </books?cursor=`+page.NextCursor+`&limit=2>; rel="next"`,
+		w.Header().Get("Link"))
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/patch"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxPatchSize bounds the body of a PATCH request
+const maxPatchSize = 1 << 20
+
+// bookEditableColumns are the columns written by PUT and PATCH, zero values included
+var bookEditableColumns = []string{"title", "author", "genre", "language", "published_at", "isbn10", "isbn13", "publisher", "page_count", "description", "edition", "series_id", "series_volume"}
+
+// editableBook returns the editable fields of book, the document PATCH requests apply to
+func editableBook(book models.Book) models.UpdateBook {
+	return models.UpdateBook{
+		Title:        book.Title,
+		Author:       book.Author,
+		Genre:        book.Genre,
+		Language:     book.Language,
+		PublishedAt:  book.PublishedAt,
+		ISBN10:       book.ISBN10,
+		ISBN13:       book.ISBN13,
+		Publisher:    book.Publisher,
+		PageCount:    book.PageCount,
+		Description:  book.Description,
+		Edition:      book.Edition,
+		SeriesID:     book.SeriesID,
+		SeriesVolume: book.SeriesVolume,
+	}
+}
+
+// decodeBook decodes and checks a CreateBook or UpdateBook, rejecting unknown fields
+func decodeBook(data []byte, input interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(input); err != nil {
+		return err
+	}
+	return checkBook(input)
+}
+
+// replaceBook overwrites all editable fields of book with input, bumps its
+// version and records revision. It fails with errVersionConflict if the
+// stored version is no longer the one book was read at.
+func (r *bookRepository) replaceBook(book *models.Book, input models.UpdateBook, revision models.BookRevision) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		return updateBook(tx, book, input, revision)
+	})
+	if err != nil {
+		return err
+	}
+	r.invalidateBookCache()
+	r.Suggestions.Invalidate()
+	return nil
+}
+
+// updateBook is replaceBook within the transaction tx, leaving book
+// unchanged if it fails
+func updateBook(tx *gorm.DB, book *models.Book, input models.UpdateBook, revision models.BookRevision) error {
+	updated := *book
+	updated.Title = input.Title
+	updated.Author = input.Author
+	updated.Genre = input.Genre
+	updated.Language = input.Language
+	updated.PublishedAt = input.PublishedAt
+	updated.ISBN10 = input.ISBN10
+	updated.ISBN13 = input.ISBN13
+	updated.Publisher = input.Publisher
+	updated.PageCount = input.PageCount
+	updated.Description = input.Description
+	updated.Edition = input.Edition
+	updated.SeriesID = input.SeriesID
+	updated.SeriesVolume = input.SeriesVolume
+	updated.Version = book.Version + 1
+
+	// Lookups are only needed when the publisher or series changes
+	if models.NameKey(updated.Publisher) != models.NameKey(book.Publisher) || book.PublisherID == nil {
+		if err := linkPublisher(tx, &updated); err != nil {
+			return err
+		}
+	}
+	if !sameID(updated.SeriesID, book.SeriesID) {
+		if err := checkSeries(tx, updated.SeriesID); err != nil {
+			return err
+		}
+	}
+
+	// Updates copies the new values into the model, so it gets a copy of book
+	model := *book
+	result := tx.Model(&model).
+		Where("version = ?", book.Version).
+		Select(append(bookEditableColumns, "publisher_id", "version")).
+		Updates(&updated)
+	if result.Error != nil {
+		return translateBookError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	// Only the model got the new updated_at
+	updated.UpdatedAt = model.UpdatedAt
+	before := editableBook(*book)
+	if err := recordRevision(tx, revision, &before, updated); err != nil {
+		return err
+	}
+	*book = updated
+	return nil
+}
+
+// writeUpdateError responds to a failed replaceBook
+func writeUpdateError(c *gin.Context, err error) {
+	if errors.Is(err, errVersionConflict) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+		return
+	}
+	if status, problems := rejectedBook(err); problems != nil {
+		writeBookError(c, status, problems)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+}
+
+// PatchBook godoc
+// @Summary Partially update a book by ID
+// @Description Apply a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to the editable fields of the book. The patched book must be valid as a whole
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being patched"
+// @Param input body models.UpdateBook true "Merge patch or JSON patch"
+// @Success 200 {object} models.Book "Successfully updated book"
+// @Failure 400 {string} string "Malformed patch"
+// @Failure 404 {string} string "book not found"
+// @Failure 409 {string} string "A JSON Patch test operation failed or the ISBN-13 is already used by another book"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 415 {string} string "Unsupported patch format"
+// @Failure 422 {string} string "The patched book is invalid"
+// @Failure 428 {string} string "If-Match header required"
+// @Router /books/{id} [patch]
+func (r *bookRepository) PatchBook(c *gin.Context) {
+	var book models.Book
+
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != patch.MergePatchContentType && contentType != patch.JSONPatchContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + patch.MergePatchContentType + " or " + patch.JSONPatchContentType})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Patch too large"})
+		return
+	}
+
+	document, err := json.Marshal(editableBook(book))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal data"})
+		return
+	}
+
+	var patched []byte
+	if contentType == patch.MergePatchContentType {
+		patched, err = patch.MergePatch(document, body)
+	} else {
+		patched, err = patch.ApplyJSONPatch(document, body)
+	}
+	if errors.Is(err, patch.ErrTestFailed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the patched document like a PUT body, rejecting fields that are not editable
+	var input models.UpdateBook
+	if err := decodeBook(patched, &input); err != nil {
+		writeBookError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := r.replaceBook(&book, input, newRevision(c, models.RevisionUpdate)); err != nil {
+		writeUpdateError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, gin.H{"data": book})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/patch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// patchTestRouter serves PatchBook on a stored book, recording the update statement
+func patchTestRouter(t *testing.T, ctrl *gomock.Controller, statement **gorm.Statement) *gin.Engine {
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil)).AnyTimes()
+	}
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB).AnyTimes()
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Genre: "Science Fiction", Version: 3}
+		return mockDB
+	}).AnyTimes()
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+	mockDB.EXPECT().Transaction(gomock.Any()).DoAndReturn(func(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+		db := dryRunDB(t)
+		db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+			*statement = tx.Statement
+			tx.RowsAffected = 1
+		})
+		return fc(db)
+	}).AnyTimes()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PATCH("/books/:id", repo.PatchBook)
+	return r
+}
+
+func TestPatchBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var statement *gorm.Statement
+	r := patchTestRouter(t, ctrl, &statement)
+
+	for _, test := range []struct {
+		contentType string
+		body        string
+	}{
+		{patch.MergePatchContentType, `{"genre": null, "language": "en"}`},
+		{patch.JSONPatchContentType, `[{"op": "test", "path": "/title", "value": "Dune"}, {"op": "replace", "path": "/genre", "value": ""}, {"op": "add", "path": "/language", "value": "en"}]`},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"genre":"","language":"en"`)
+		// Cleared fields are written too
+		assert.Equal(t,
+			`UPDATE "books" SET "title"=$1,"author"=$2,"genre"=$3,"language"=$4,"published_at"=$5,"isbn10"=$6,"isbn13"=$7,"publisher"=$8,"page_count"=$9,"description"=$10,"edition"=$11,"publisher_id"=$12,"series_id"=$13,"series_volume"=$14,"version"=$15,"updated_at"=$16 WHERE version = $17 AND "books"."deleted_at" IS NULL AND "id" = $18`,
+			statement.SQL.String())
+		assert.Equal(t, "", statement.Vars[2])
+		assert.Equal(t, uint(4), statement.Vars[14])
+		assert.Equal(t, uint(3), statement.Vars[16])
+		assert.Equal(t, `"1-4"`, w.Header().Get("ETag"))
+	}
+}
+
+func TestPatchBookErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var statement *gorm.Statement
+	r := patchTestRouter(t, ctrl, &statement)
+
+	for _, test := range []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"application/json", `{"genre": "Fantasy"}`, http.StatusUnsupportedMediaType},
+		{patch.MergePatchContentType, `{"genre":`, http.StatusBadRequest},
+		{patch.JSONPatchContentType, `[{"op": "remove", "path": "/isbn"}]`, http.StatusBadRequest},
+		{patch.JSONPatchContentType, `[{"op": "test", "path": "/title", "value": "Emma"}]`, http.StatusConflict},
+		{patch.MergePatchContentType, `{"title": null}`, http.StatusUnprocessableEntity},
+		{patch.MergePatchContentType, `{"id": 2}`, http.StatusUnprocessableEntity},
+		{patch.MergePatchContentType, `{"published_at": "yesterday"}`, http.StatusUnprocessableEntity},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, test.status, w.Code, test.body)
+	}
+	assert.Nil(t, statement)
+}
+
+func TestUpdateBookUpdatedAt(t *testing.T) {
+	db := dryRunDB(t)
+	var statement *gorm.Statement
+	db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+		statement = tx.Statement
+		tx.RowsAffected = 1
+	})
+	stale := time.Now().Add(-time.Hour)
+	book := models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Version: 3, UpdatedAt: stale}
+
+	err := updateBook(db, &book, models.UpdateBook{Title: "Dune", Author: "Frank Herbert", Genre: "Science Fiction"}, models.BookRevision{})
+
+	assert.NoError(t, err)
+	assert.True(t, book.UpdatedAt.After(stale))
+	// The response and its ETag show the time that was written
+	assert.Equal(t, statement.Vars[15], book.UpdatedAt)
+}
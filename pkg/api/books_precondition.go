@@ -0,0 +1,123 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errVersionConflict is returned when a book changed between reading and writing it
+var errVersionConflict = errors.New("book was modified concurrently")
+
+// requireIfMatch reports whether writes to a book must send If-Match
+func requireIfMatch() bool {
+	return os.Getenv("REQUIRE_IF_MATCH") == "true"
+}
+
+// bookETag is the strong entity tag of a version of a book
+func bookETag(book models.Book) string {
+	return `"` + strconv.FormatUint(uint64(book.ID), 10) + "-" + strconv.FormatUint(uint64(book.Version), 10) + `"`
+}
+
+// checkIfMatch evaluates the If-Match header against book. It responds with
+// 428 if the header is required but missing and 412 if no tag matches, and
+// then returns false.
+func (r *bookRepository) checkIfMatch(c *gin.Context, book models.Book) bool {
+	switch r.ifMatchFailure(c.GetHeader("If-Match"), book) {
+	case http.StatusPreconditionRequired:
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required"})
+		return false
+	case http.StatusPreconditionFailed:
+		c.Header("ETag", bookETag(book))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Book has been modified"})
+		return false
+	}
+	return true
+}
+
+// ifMatchFailure returns the status of a failed If-Match precondition on
+// book, or 0 if it holds
+func (r *bookRepository) ifMatchFailure(header string, book models.Book) int {
+	if header == "" {
+		if r.RequireIfMatch {
+			return http.StatusPreconditionRequired
+		}
+		return 0
+	}
+	if !etagMatches(header, bookETag(book), false) {
+		return http.StatusPreconditionFailed
+	}
+	return 0
+}
+
+// etagMatches reports whether a list of entity tags from an If-Match or
+// If-None-Match header contains etag. Weak tags only match with weak
+// comparison (RFC 9110, section 8.8.3.2).
+func etagMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bookCacheControl lets clients store book responses but makes them revalidate
+// before every use. Responses depend on credentials, so shared caches must not store them.
+const bookCacheControl = "private, no-cache"
+
+// contentETag is a strong entity tag over a response body
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// setCacheValidators sets the ETag and Cache-Control headers and, unless it is
+// zero, Last-Modified
+func setCacheValidators(c *gin.Context, etag string, lastModified time.Time) {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", bookCacheControl)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates If-None-Match and, only without it, If-Modified-Since
+// (RFC 9110, section 13.2.2) for a GET request. If the client's copy is
+// current it responds with 304 and returns true.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	current := false
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		current = etagMatches(header, etag, true)
+	} else if header := c.GetHeader("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		current = err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if current {
+		c.Status(http.StatusNotModified)
+	}
+	return current
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/patch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := bookETag(models.Book{ID: 1, Version: 3})
+	assert.Equal(t, `"1-3"`, etag)
+
+	assert.True(t, etagMatches(`"1-3"`, etag, false))
+	assert.True(t, etagMatches(`"1-2", "1-3"`, etag, false))
+	assert.True(t, etagMatches(`*`, etag, false))
+	assert.False(t, etagMatches(`"1-2"`, etag, false))
+	// Weak tags never match strongly
+	assert.False(t, etagMatches(`W/"1-3"`, etag, false))
+	assert.True(t, etagMatches(`W/"1-3"`, etag, true))
+}
+
+func TestPatchBookPreconditions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Setenv("REQUIRE_IF_MATCH", "true")
+	var statement *gorm.Statement
+	r := patchTestRouter(t, ctrl, &statement)
+
+	for _, test := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{"", http.StatusPreconditionRequired},
+		{`"1-2"`, http.StatusPreconditionFailed},
+		{`W/"1-3"`, http.StatusPreconditionFailed},
+		{`"1-3"`, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPatch, "/books/1", strings.NewReader(`{"genre": "Fantasy"}`))
+		req.Header.Set("Content-Type", patch.MergePatchContentType)
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, test.status, w.Code, test.ifMatch)
+	}
+}
+
+func TestDeleteBookConcurrentUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/books/:id", repo.DeleteBook)
+
+	book := models.Book{ID: 1, Title: "Dune", Version: 3}
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = book
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+	// The book was updated after it was read, so no row is deleted
+	expectTransaction(mockDB, dryRunDB(t))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/books/1", nil)
+	req.Header.Set("If-Match", `"1-3"`)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestFindBookConditional(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/:id", repo.FindBook)
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB).AnyTimes()
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = models.Book{ID: 1, Title: "Dune", Version: 3, UpdatedAt: updatedAt}
+		return mockDB
+	}).AnyTimes()
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+
+	for _, test := range []struct {
+		header, value string
+		status        int
+	}{
+		{"", "", http.StatusOK},
+		{"If-None-Match", `"1-3"`, http.StatusNotModified},
+		{"If-None-Match", `W/"1-3", "other"`, http.StatusNotModified},
+		{"If-None-Match", `"1-2"`, http.StatusOK},
+		{"If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT", http.StatusNotModified},
+		{"If-Modified-Since", "Wed, 01 May 2024 11:59:59 GMT", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/books/1", nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, test.status, w.Code, test.value)
+		assert.Equal(t, `"1-3"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, bookCacheControl, w.Header().Get("Cache-Control"))
+		if test.status == http.StatusNotModified {
+			assert.Empty(t, w.Body.String())
+		}
+	}
+}
+
+func TestFindBooksConditional(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), mockCache, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	cachedPage, _ := json.Marshal(bookPage{Data: []models.Book{{ID: 1, Title: "Dune", Version: 3}}})
+	mockCache.EXPECT().Get(ctx, "books_offset_0_limit_10").Return(redis.NewStringResult(string(cachedPage), nil)).Times(2)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, contentETag(w.Body.Bytes()), etag)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("If-None-Match", etag)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxFilterIDs bounds the size of the ids filter
+const maxFilterIDs = 100
+
+// bookSortColumns whitelists the fields books can be sorted by
+var bookSortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"author":     "author",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// bookTimeFilters maps the range query parameters to their column and comparison
+var bookTimeFilters = []struct {
+	param    string
+	column   string
+	operator string
+}{
+	{"created_after", "created_at", ">="},
+	{"created_before", "created_at", "<"},
+	{"updated_after", "updated_at", ">="},
+	{"updated_before", "updated_at", "<"},
+}
+
+type sortField struct {
+	Column string
+	Desc   bool
+}
+
+// bookQuery holds the validated filter and sort parameters of a book listing
+type bookQuery struct {
+	Author   string
+	Title    string
+	Genre    string
+	Language string
+	Decade   *int
+	Times    map[string]time.Time
+	IDs      []uint
+	// PublisherID and SeriesID filter by the publisher and series books are linked to
+	PublisherID *uint
+	SeriesID    *uint
+	Sort        []sortField
+}
+
+// parseBookQuery reads the whitelisted filter and sort parameters of a request
+func parseBookQuery(c *gin.Context) (*bookQuery, error) {
+	return parseBookValues(c.Request.URL.Query())
+}
+
+// parseBookValues reads the whitelisted filter and sort parameters from values
+func parseBookValues(values url.Values) (*bookQuery, error) {
+	query := &bookQuery{
+		Author:   strings.ToLower(strings.TrimSpace(values.Get("author"))),
+		Title:    strings.ToLower(strings.TrimSpace(values.Get("title"))),
+		Genre:    strings.ToLower(strings.TrimSpace(values.Get("genre"))),
+		Language: strings.ToLower(strings.TrimSpace(values.Get("language"))),
+		Times:    make(map[string]time.Time),
+	}
+
+	if value := values.Get("decade"); value != "" {
+		decade, err := strconv.Atoi(value)
+		if err != nil || decade%10 != 0 {
+			return nil, errors.New("Invalid decade, expected a year like 1990")
+		}
+		query.Decade = &decade
+	}
+
+	for _, filter := range bookTimeFilters {
+		value := values.Get(filter.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s format", filter.param)
+		}
+		query.Times[filter.param] = parsed
+	}
+
+	var err error
+	if query.PublisherID, err = parseIDParam(values, "publisher_id"); err != nil {
+		return nil, err
+	}
+	if query.SeriesID, err = parseIDParam(values, "series_id"); err != nil {
+		return nil, err
+	}
+
+	if ids := values.Get("ids"); ids != "" {
+		seen := make(map[uint]bool)
+		for _, part := range strings.Split(ids, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return nil, errors.New("Invalid ids format")
+			}
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				query.IDs = append(query.IDs, uint(id))
+			}
+		}
+		if len(query.IDs) > maxFilterIDs {
+			return nil, fmt.Errorf("Too many ids, at most %d are allowed", maxFilterIDs)
+		}
+		sort.Slice(query.IDs, func(i, j int) bool { return query.IDs[i] < query.IDs[j] })
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = "id"
+	}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sortParam, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		column, ok := bookSortColumns[strings.TrimPrefix(part, "-")]
+		if !ok {
+			return nil, fmt.Errorf("Invalid sort field: %s", part)
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		query.Sort = append(query.Sort, sortField{Column: column, Desc: desc})
+	}
+	// Always end on the primary key so the order is total and stable between pages
+	if !seen["id"] {
+		query.Sort = append(query.Sort, sortField{Column: "id"})
+	}
+
+	return query, nil
+}
+
+// parseIDParam reads an optional ID parameter
+func parseIDParam(values url.Values, param string) (*uint, error) {
+	value := values.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s format", param)
+	}
+	parsed := uint(id)
+	return &parsed, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates
+func parseTimeParam(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// filter is a gorm scope applying the filters of the query
+func (q *bookQuery) filter(db *gorm.DB) *gorm.DB {
+	if q.Author != "" {
+		db = db.Where("LOWER(author) = ?", q.Author)
+	}
+	if q.Title != "" {
+		db = db.Where(`LOWER(title) LIKE ? ESCAPE '\'`, "%"+escapeLike(q.Title)+"%")
+	}
+	if q.Genre != "" {
+		db = db.Where("LOWER(genre) = ?", q.Genre)
+	}
+	if q.Language != "" {
+		db = db.Where("LOWER(language) = ?", q.Language)
+	}
+	if q.Decade != nil {
+		db = db.Where("published_at >= ? AND published_at < ?",
+			time.Date(*q.Decade, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(*q.Decade+10, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	for _, filter := range bookTimeFilters {
+		if value, ok := q.Times[filter.param]; ok {
+			db = db.Where(filter.column+" "+filter.operator+" ?", value)
+		}
+	}
+	if len(q.IDs) > 0 {
+		db = db.Where("id IN ?", q.IDs)
+	}
+	if q.PublisherID != nil {
+		db = db.Where("publisher_id = ?", *q.PublisherID)
+	}
+	if q.SeriesID != nil {
+		db = db.Where("series_id = ?", *q.SeriesID)
+	}
+	return db
+}
+
+// order is a gorm scope applying the sort of the query
+func (q *bookQuery) order(db *gorm.DB) *gorm.DB {
+	for _, field := range q.Sort {
+		if field.Desc {
+			db = db.Order(field.Column + " DESC")
+		} else {
+			db = db.Order(field.Column)
+		}
+	}
+	return db
+}
+
+// sortParam returns the normalized sort parameter, e.g. -created_at,title,id
+func (q *bookQuery) sortParam() string {
+	fields := make([]string, len(q.Sort))
+	for i, field := range q.Sort {
+		fields[i] = field.Column
+		if field.Desc {
+			fields[i] = "-" + field.Column
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// normalized encodes the query canonically so that equivalent requests share a cache key
+func (q *bookQuery) normalized() string {
+	values := url.Values{}
+	if q.Author != "" {
+		values.Set("author", q.Author)
+	}
+	if q.Title != "" {
+		values.Set("title", q.Title)
+	}
+	if q.Genre != "" {
+		values.Set("genre", q.Genre)
+	}
+	if q.Language != "" {
+		values.Set("language", q.Language)
+	}
+	if q.Decade != nil {
+		values.Set("decade", strconv.Itoa(*q.Decade))
+	}
+	for param, value := range q.Times {
+		values.Set(param, value.UTC().Format(time.RFC3339))
+	}
+	if len(q.IDs) > 0 {
+		ids := make([]string, len(q.IDs))
+		for i, id := range q.IDs {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		values.Set("ids", strings.Join(ids, ","))
+	}
+	if q.PublisherID != nil {
+		values.Set("publisher_id", strconv.FormatUint(uint64(*q.PublisherID), 10))
+	}
+	if q.SeriesID != nil {
+		values.Set("series_id", strconv.FormatUint(uint64(*q.SeriesID), 10))
+	}
+	if sortParam := q.sortParam(); sortParam != "id" {
+		values.Set("sort", sortParam)
+	}
+	return values.Encode()
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
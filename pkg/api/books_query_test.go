@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestQuery(t *testing.T, rawQuery string) (*bookQuery, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/books?"+rawQuery, nil)
+	return parseBookQuery(c)
+}
+
+func TestParseBookQueryNormalizesEquivalentQueries(t *testing.T) {
+	first, err := parseTestQuery(t, "author=Jane%20Doe&ids=3,1,3&sort=-created_at,title")
+	assert.NoError(t, err)
+	second, err := parseTestQuery(t, "sort=-created_at,title,id&ids=1,3&author=jane%20doe")
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.normalized(), second.normalized())
+	assert.Equal(t, []uint{1, 3}, first.IDs)
+
+	unfiltered, err := parseTestQuery(t, "offset=0&limit=10")
+	assert.NoError(t, err)
+	assert.Equal(t, "", unfiltered.normalized())
+}
+
+func TestParseBookQueryRejectsInvalidParams(t *testing.T) {
+	for _, rawQuery := range []string{
+		"sort=password",
+		"sort=title%3BDROP%20TABLE%20books",
+		"ids=1,abc",
+		"created_after=yesterday",
+		"decade=1995",
+		"series_id=-1",
+	} {
+		_, err := parseTestQuery(t, rawQuery)
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestBookQueryScopes(t *testing.T) {
+	query, err := parseTestQuery(t, "author=Jane&title=50%25_off&created_after=2024-01-01&ids=2,1&sort=-updated_at")
+	assert.NoError(t, err)
+
+	var books []models.Book
+	stmt := dryRunDB(t).Scopes(query.filter, query.order).Find(&books).Statement
+
+	assert.Equal(t,
+		`SELECT * FROM "books" WHERE LOWER(author) = $1 AND LOWER(title) LIKE $2 ESCAPE '\' AND created_at >= $3 AND id IN ($4,$5) AND "books"."deleted_at" IS NULL ORDER BY updated_at DESC,id`,
+		stmt.SQL.String())
+	assert.Equal(t, `%50\%\_off%`, stmt.Vars[1])
+
+	query, err = parseTestQuery(t, "genre=Fantasy&decade=1950")
+	assert.NoError(t, err)
+	stmt = dryRunDB(t).Scopes(query.filter).Find(&books).Statement
+
+	assert.Equal(t, `SELECT * FROM "books" WHERE LOWER(genre) = $1 AND (published_at >= $2 AND published_at < $3) AND "books"."deleted_at" IS NULL`, stmt.SQL.String())
+	assert.Equal(t, "decade=1950&genre=fantasy", query.normalized())
+
+	query, err = parseTestQuery(t, "series_id=3&publisher_id=7")
+	assert.NoError(t, err)
+	stmt = dryRunDB(t).Scopes(query.filter).Find(&books).Statement
+
+	assert.Equal(t, `SELECT * FROM "books" WHERE publisher_id = $1 AND series_id = $2 AND "books"."deleted_at" IS NULL`, stmt.SQL.String())
+	assert.Equal(t, "publisher_id=7&series_id=3", query.normalized())
+}
+
+func TestFindBooksUsesNormalizedCacheKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), mockCache, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books", repo.FindBooks)
+
+	cachedData, _ := json.Marshal(bookPage{Data: []models.Book{{Title: "Book One", Author: "Jane"}}})
+	mockCache.EXPECT().Get(ctx, "books_offset_0_limit_10_author=jane&sort=-title%2Cid").Return(redis.NewStringResult(string(cachedData), nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books?offset=0&limit=10&sort=-title&author=JANE", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Book One")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books?sort=isbn", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// fieldChange is the value of a field before and after a revision
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// bookHistoryEntry is a revision with the changes relative to the revision before it
+type bookHistoryEntry struct {
+	models.BookRevision
+	Changes map[string]fieldChange `json:"changes"`
+}
+
+// newRevision starts the revision recording action by the client of the request
+func newRevision(c *gin.Context, action string) models.BookRevision {
+	return models.BookRevision{
+		Action:    action,
+		Actor:     actor(c),
+		RequestID: c.GetString("request_id"),
+	}
+}
+
+// actor identifies who authenticated the request: a user, an OAuth2 client or a signing key
+func actor(c *gin.Context) string {
+	if username := c.GetString("username"); username != "" {
+		return username
+	}
+	if clientID := c.GetString("client_id"); clientID != "" {
+		return "client:" + clientID
+	}
+	if keyID := c.GetString("signing_key_id"); keyID != "" {
+		return "key:" + keyID
+	}
+	return ""
+}
+
+// recordRevision completes revision with the state of book after the change
+// and stores it. before is the state of the book before the change, nil if
+// the book was created.
+func recordRevision(tx *gorm.DB, revision models.BookRevision, before *models.UpdateBook, book models.Book) error {
+	if before == nil {
+		before = &models.UpdateBook{}
+	}
+	previous, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(editableBook(book))
+	if err != nil {
+		return err
+	}
+	changes, err := diffSnapshots(previous, snapshot)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	revision.BookID = book.ID
+	revision.Version = book.Version
+	revision.Snapshot = snapshot
+	revision.ChangedFields = strings.Join(fields, ",")
+	return tx.Create(&revision).Error
+}
+
+// diffSnapshots returns the fields that differ between two snapshots
+func diffSnapshots(before, after json.RawMessage) (map[string]fieldChange, error) {
+	var from, to map[string]interface{}
+	if err := json.Unmarshal(before, &from); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &to); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]fieldChange)
+	for field, value := range to {
+		if !reflect.DeepEqual(from[field], value) {
+			changes[field] = fieldChange{From: from[field], To: value}
+		}
+	}
+	for field, value := range from {
+		if _, ok := to[field]; !ok {
+			changes[field] = fieldChange{From: value}
+		}
+	}
+	return changes, nil
+}
+
+// BookHistory godoc
+// @Summary Get the revision history of a book
+// @Description List the revisions of a book, newest first, with the fields each revision changed
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} bookHistoryEntry "Revisions of the book"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "book not found"
+// @Router /books/{id}/history [get]
+func (r *bookRepository) BookHistory(c *gin.Context) {
+	var book models.Book
+
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	// The revision before the last one on the page is needed for its changes
+	revisions := []models.BookRevision{}
+	err = r.DB.Where("book_id = ?", book.ID).Order("id DESC").Offset(offset).Limit(limit + 1).Find(&revisions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	// The first revision is compared to an empty book
+	empty, err := json.Marshal(models.UpdateBook{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal data"})
+		return
+	}
+
+	history := make([]bookHistoryEntry, 0, min(len(revisions), limit))
+	for i := 0; i < len(revisions) && i < limit; i++ {
+		previous := json.RawMessage(empty)
+		if i+1 < len(revisions) {
+			previous = revisions[i+1].Snapshot
+		}
+		changes, err := diffSnapshots(previous, revisions[i].Snapshot)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare revisions"})
+			return
+		}
+		history = append(history, bookHistoryEntry{BookRevision: revisions[i], Changes: changes})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
+
+// RevertBook godoc
+// @Summary Revert a book to an earlier revision
+// @Description Restore the editable fields of the book to their state after the given revision. The revert is recorded as a new revision
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param revision path string true "Revision ID"
+// @Param If-Match header string false "ETag of the version being reverted"
+// @Success 200 {object} models.Book "Reverted book"
+// @Header 200 {string} ETag "Entity tag of the new version"
+// @Failure 404 {string} string "book not found"
+// @Failure 409 {string} string "ISBN-13 already used by another book"
+// @Failure 412 {string} string "Book has been modified"
+// @Failure 422 {string} string "The revision is no longer a valid book"
+// @Failure 428 {string} string "If-Match header required"
+// @Router /books/{id}/revert/{revision} [post]
+func (r *bookRepository) RevertBook(c *gin.Context) {
+	var book models.Book
+	var revision models.BookRevision
+
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&book).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		return
+	}
+
+	if !r.checkIfMatch(c, book) {
+		return
+	}
+
+	if err := r.DB.Where("id = ? AND book_id = ?", c.Param("revision"), book.ID).First(&revision).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+		return
+	}
+
+	// Fields added since the revision was recorded are cleared
+	var input models.UpdateBook
+	if err := json.Unmarshal(revision.Snapshot, &input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read revision"})
+		return
+	}
+	if err := checkBook(&input); err != nil {
+		writeBookError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	revert := newRevision(c, models.RevisionRevert)
+	revert.RevertedFrom = &revision.ID
+	if err := r.replaceBook(&book, input, revert); err != nil {
+		writeUpdateError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, gin.H{"data": book})
+}
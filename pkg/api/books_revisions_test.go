@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	changes, err := diffSnapshots(
+		json.RawMessage(`{"title": "Dune", "author": "Frank Herbert", "genre": "SF"}`),
+		json.RawMessage(`{"title": "Dune Messiah", "author": "Frank Herbert", "language": "en"}`),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]fieldChange{
+		"title":    {From: "Dune", To: "Dune Messiah"},
+		"genre":    {From: "SF"},
+		"language": {To: "en"},
+	}, changes)
+}
+
+func TestBookHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = models.Book{ID: 1, Title: "Dune Messiah", Version: 2}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	// Newest first, with one revision more than the limit
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		*tx.Statement.Dest.(*[]models.BookRevision) = []models.BookRevision{
+			{ID: 3, BookID: 1, Action: models.RevisionUpdate, Version: 3, Snapshot: json.RawMessage(`{"title": "Dune Messiah", "author": "Frank Herbert"}`)},
+			{ID: 2, BookID: 1, Action: models.RevisionUpdate, Version: 2, Snapshot: json.RawMessage(`{"title": "Dune", "author": "Frank Herbert"}`)},
+		}
+	})
+	mockDB.EXPECT().Where("book_id = ?", uint(1)).Return(&database.GormDatabase{DB: db})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/:id/history", repo.BookHistory)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/1/history?limit=1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []bookHistoryEntry `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, uint(3), response.Data[0].ID)
+	assert.Equal(t, map[string]fieldChange{"title": {From: "Dune", To: "Dune Messiah"}}, response.Data[0].Changes)
+}
+
+func TestRevertBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB)
+	mockDB.EXPECT().Where("id = ? AND book_id = ?", "2", uint(1)).Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		switch dest := dest.(type) {
+		case *models.Book:
+			*dest = models.Book{ID: 1, Title: "Dune Messiah", Author: "Frank Herbert", Version: 3}
+		case *models.BookRevision:
+			*dest = models.BookRevision{ID: 2, BookID: 1, Snapshot: json.RawMessage(`{"title": "Dune", "author": "Frank Herbert", "genre": "SF"}`)}
+		}
+		return mockDB
+	}).Times(2)
+	mockDB.EXPECT().Error().Return(nil).Times(2)
+
+	db := dryRunDB(t)
+	db.Callback().Update().After("gorm:update").Register("test:rows", func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	var revision *models.BookRevision
+	db.Callback().Create().After("gorm:create").Register("test:revision", func(tx *gorm.DB) {
+		revision = tx.Statement.Dest.(*models.BookRevision)
+	})
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/revert/:revision", authenticatedAs("jane"), repo.RevertBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/1/revert/2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"title":"Dune","author":"Frank Herbert","genre":"SF"`)
+	assert.Equal(t, `"1-4"`, w.Header().Get("ETag"))
+	assert.Equal(t, models.RevisionRevert, revision.Action)
+	assert.Equal(t, uint(2), *revision.RevertedFrom)
+	assert.Equal(t, "genre,title", revision.ChangedFields)
+	assert.Equal(t, "jane", revision.Actor)
+}
+
+// authenticatedAs stands in for JWTAuth authenticating username
+func authenticatedAs(username string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("username", username)
+		c.Next()
+	}
+}
+
+func TestUpdateBookRecordsActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Book) = models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Version: 1}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	db := dryRunDB(t)
+	db.Callback().Update().After("gorm:update").Register("test:rows", func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	var revision *models.BookRevision
+	db.Callback().Create().After("gorm:create").Register("test:revision", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(*models.BookRevision); ok {
+			revision = dest
+		}
+	})
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.PUT("/books/:id", authenticatedAs("jane"), repo.UpdateBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/books/1", strings.NewReader(`{"title": "Dune Messiah", "author": "Frank Herbert"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, models.RevisionUpdate, revision.Action)
+	assert.Equal(t, "jane", revision.Actor)
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"golang-rest-api-template/pkg/models"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits of a search query
+const (
+	maxSearchTerms = 10
+	// maxSearchCandidates bounds the rows ranked in memory by the fallback search
+	maxSearchCandidates = 1000
+)
+
+// Highlighted matches are wrapped in these tags, the rest of the text is HTML escaped
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// bookSearchSQL ranks and highlights books matching a prefix tsquery. Title and
+// author are HTML escaped before ts_headline adds the highlight tags.
+const bookSearchSQL = `SELECT books.*,
+	ts_rank(search_vector, query) AS rank,
+	ts_headline('english', replace(replace(replace(title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+	ts_headline('english', replace(replace(replace(author, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS author_highlight
+FROM books, to_tsquery('english', ?) query
+WHERE search_vector @@ query AND deleted_at IS NULL
+ORDER BY rank DESC, id
+LIMIT ? OFFSET ?`
+
+// collapsedBookSearchSQL is bookSearchSQL keeping only the best ranked edition
+// of each work, with the number of its editions that match. Only the kept
+// editions are highlighted.
+const collapsedBookSearchSQL = `SELECT best.*,
+	ts_headline('english', replace(replace(replace(title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+	ts_headline('english', replace(replace(replace(author, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS author_highlight
+FROM (
+	SELECT DISTINCT ON (COALESCE(work_id, -id)) books.*, query,
+		ts_rank(search_vector, query) AS rank,
+		count(*) OVER (PARTITION BY COALESCE(work_id, -id)) AS editions
+	FROM books, to_tsquery('english', ?) query
+	WHERE search_vector @@ query AND deleted_at IS NULL
+	ORDER BY COALESCE(work_id, -id), rank DESC, id
+) best
+ORDER BY rank DESC, id
+LIMIT ? OFFSET ?`
+
+// bookSearchResult is a book matching a search with its relevance and highlighted fields
+type bookSearchResult struct {
+	models.Book
+	Rank            float64 `json:"rank"`
+	TitleHighlight  string  `json:"title_highlight"`
+	AuthorHighlight string  `json:"author_highlight"`
+	// Editions counts the matching editions of the work when editions are collapsed
+	Editions int `json:"editions,omitempty"`
+}
+
+// SearchBooks godoc
+// @Summary Search books
+// @Description Full-text search over the title and author of books, ranked by relevance. Words are stemmed and match as prefixes, matches are highlighted with <mark> tags in the HTML escaped title_highlight and author_highlight. Only the best matching edition of each work is listed, with the number of matching editions, unless collapse is false
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string true "Search query"
+// @Param collapse query bool false "Collapse the editions of a work into one result" default(true)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Param facets query string false "Comma separated facets to count matching books by (author, decade, genre, language)"
+// @Param facet_limit query int false "Values per facet, at most 50" default(10)
+// @Success 200 {array} bookSearchResult "Matching books, most relevant first"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/search [get]
+func (r *bookRepository) SearchBooks(c *gin.Context) {
+	terms := searchTerms(c.Query("q"))
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	facets, err := parseFacetParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	collapse := true
+	if value := c.Query("collapse"); value != "" {
+		if collapse, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collapse, expected true or false"})
+			return
+		}
+	}
+
+	var results []bookSearchResult
+	postgres := r.DB.Dialect() == "postgres"
+	switch {
+	case postgres && collapse:
+		err = r.DB.Raw(collapsedBookSearchSQL, prefixTSQuery(terms), limit, offset).Find(&results).Error
+	case postgres:
+		err = r.DB.Raw(bookSearchSQL, prefixTSQuery(terms), limit, offset).Find(&results).Error
+	default:
+		results, err = r.searchBooksFallback(terms, collapse, limit, offset)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		return
+	}
+	if results == nil {
+		results = []bookSearchResult{}
+	}
+
+	if facets == nil {
+		c.JSON(http.StatusOK, gin.H{"data": results})
+		return
+	}
+	facetResults, err := r.loadFacets(facets, url.Values{"q": {strings.Join(terms, " ")}}.Encode(), searchFilter(terms, postgres))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count facets"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": results, "facets": facetResults})
+}
+
+// searchTerms splits a query into at most maxSearchTerms distinct lower case
+// words. Everything but letters and digits is dropped so the words are safe
+// to use in a tsquery.
+func searchTerms(q string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// prefixTSQuery requires every term, each matching as a prefix, e.g. tolk:* & ring:*
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// searchFilter is a gorm scope selecting the books matching every term
+func searchFilter(terms []string, postgres bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if postgres {
+			return db.Where("search_vector @@ to_tsquery('english', ?)", prefixTSQuery(terms))
+		}
+		for _, term := range terms {
+			pattern := "%" + escapeLike(term) + "%"
+			db = db.Where(`(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(author) LIKE ? ESCAPE '\')`, pattern, pattern)
+		}
+		return db
+	}
+}
+
+// searchBooksFallback serves databases without full-text search. Books must
+// contain every term in their title or author; they are ranked by the number
+// of matches, title matches counting double.
+func (r *bookRepository) searchBooksFallback(terms []string, collapse bool, limit, offset int) ([]bookSearchResult, error) {
+	var books []models.Book
+	if err := r.DB.Scopes(searchFilter(terms, false)).Order("id").Limit(maxSearchCandidates).Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]bookSearchResult, len(books))
+	for i, book := range books {
+		results[i] = bookSearchResult{
+			Book:            book,
+			Rank:            float64(2*countMatches(book.Title, terms) + countMatches(book.Author, terms)),
+			TitleHighlight:  highlight(book.Title, terms),
+			AuthorHighlight: highlight(book.Author, terms),
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if collapse {
+		results = collapseEditions(results)
+	}
+
+	if offset >= len(results) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// collapseEditions keeps the first of the ranked results of each work and
+// counts the editions it stands for
+func collapseEditions(results []bookSearchResult) []bookSearchResult {
+	type workKey struct{ work, book uint }
+	kept := make(map[workKey]int)
+	var collapsed []bookSearchResult
+	for _, result := range results {
+		key := workKey{book: result.ID}
+		if result.WorkID != nil {
+			key = workKey{work: *result.WorkID}
+		}
+		if i, ok := kept[key]; ok {
+			collapsed[i].Editions++
+			continue
+		}
+		kept[key] = len(collapsed)
+		result.Editions = 1
+		collapsed = append(collapsed, result)
+	}
+	return collapsed
+}
+
+func countMatches(text string, terms []string) int {
+	text = strings.ToLower(text)
+	count := 0
+	for _, term := range terms {
+		count += strings.Count(text, term)
+	}
+	return count
+}
+
+// highlight HTML escapes text and marks the occurrences of terms
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	// Only byte offsets that are the same in the lower cased text can be mapped back
+	if len(lower) != len(text) {
+		return html.EscapeString(text)
+	}
+
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		for start := 0; start < len(lower); {
+			index := strings.Index(lower[start:], term)
+			if index < 0 {
+				break
+			}
+			for k := start + index; k < start+index+len(term); k++ {
+				marked[k] = true
+			}
+			start += index + len(term)
+		}
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			builder.WriteString(highlightStart + html.EscapeString(text[i:j]) + highlightStop)
+		} else {
+			builder.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return builder.String()
+}
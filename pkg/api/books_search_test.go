@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"lord", "of", "rings"}, searchTerms("Lord | of' & RINGS:* rings!"))
+	assert.Equal(t, "tolk:* & ring:*", prefixTSQuery(searchTerms("tolk & ring:*")))
+	assert.Empty(t, searchTerms(" !& "))
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "The <mark>Hob</mark>bit &amp; <mark>hob</mark>s", highlight("The Hobbit & hobs", []string{"hob"}))
+	assert.Equal(t, "&lt;b&gt;<mark>Dune</mark>&lt;/b&gt;", highlight("<b>Dune</b>", []string{"dune"}))
+}
+
+func TestSearchBooksPostgres(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/search", repo.SearchBooks)
+
+	var statement *gorm.Statement
+	raw := func(sql string, values ...interface{}) *gorm.DB {
+		db := dryRunDB(t).Raw(sql, values...)
+		statement = db.Statement
+		return db
+	}
+	mockDB.EXPECT().Dialect().Return("postgres").Times(2)
+	mockDB.EXPECT().Raw(collapsedBookSearchSQL, "hobbit:* & tolk:*", 5, 10).DoAndReturn(raw)
+	mockDB.EXPECT().Raw(bookSearchSQL, "hobbit:*", 10, 0).DoAndReturn(raw)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/search?q=Hobbit+Tolk&limit=5&offset=10", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, statement.SQL.String(), "WHERE search_vector @@ query")
+	assert.JSONEq(t, `{"data": []}`, w.Body.String())
+
+	// Every edition is listed on request
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books/search?q=Hobbit&collapse=false", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, statement.SQL.String(), "DISTINCT ON")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books/search?q=%26%26", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchBooksFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/search", repo.SearchBooks)
+
+	work := uint(7)
+	db := dryRunDBWithRows(t, []models.Book{
+		{ID: 1, Title: "Letters", Author: "J. R. R. Tolkien"},
+		{ID: 2, Title: "Tolkien", Author: "Humphrey Carpenter", WorkID: &work},
+		{ID: 3, Title: "Tolkien: A Biography", Author: "Humphrey Carpenter", WorkID: &work},
+	})
+	mockDB.EXPECT().Dialect().Return("sqlite")
+	mockDB.EXPECT().Scopes(gomock.Any()).Return(db)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/search?q=tolkien", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []bookSearchResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data, 2)
+	// Title matches rank first, the other edition of the work is collapsed into it
+	assert.Equal(t, uint(2), response.Data[0].ID)
+	assert.Equal(t, 2, response.Data[0].Editions)
+	assert.Equal(t, "<mark>Tolkien</mark>", response.Data[0].TitleHighlight)
+	assert.Equal(t, "J. R. R. <mark>Tolkien</mark>", response.Data[1].AuthorHighlight)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/search"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSuggestions caps the limit of the suggest endpoint
+const maxSuggestions = 20
+
+// suggestIndexMaxAge bounds how long the suggestion index of one instance can
+// miss changes made through another instance
+const suggestIndexMaxAge = 5 * time.Minute
+
+// SuggestBooks godoc
+// @Summary Autocomplete titles and authors
+// @Description Suggest titles and authors completing the text typed so far. Words of the query match the start of any word, queries of four or more characters tolerate a typo
+// @Tags books
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string true "Text typed so far"
+// @Param limit query int false "Number of suggestions, at most 20" default(10)
+// @Success 200 {array} search.Suggestion "Suggestions, best first"
+// @Failure 400 {string} string "Bad Request"
+// @Router /books/suggest [get]
+func (r *bookRepository) SuggestBooks(c *gin.Context) {
+	q := search.Normalize(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit = min(limit, maxSuggestions)
+
+	cacheKey := "books_suggest_" + url.QueryEscape(q) + "_limit_" + strconv.Itoa(limit)
+
+	var suggestions []search.Suggestion
+	cachedSuggestions, err := r.RedisClient.Get(*r.Ctx, cacheKey).Result()
+	if err == nil {
+		if err := json.Unmarshal([]byte(cachedSuggestions), &suggestions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal cached data"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": suggestions})
+		return
+	}
+
+	if err := r.Suggestions.Refresh(suggestIndexMaxAge, r.loadSuggestions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suggestions"})
+		return
+	}
+	suggestions = r.Suggestions.Suggest(q, limit)
+
+	serializedSuggestions, err := json.Marshal(suggestions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal data"})
+		return
+	}
+	err = r.RedisClient.Set(*r.Ctx, cacheKey, serializedSuggestions, time.Minute).Err()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set cache"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": suggestions})
+}
+
+// suggestionColumns are the book columns completed by the suggestion index, with their kind
+var suggestionColumns = []struct {
+	column string
+	kind   string
+}{
+	{"title", search.KindTitle},
+	{"author", search.KindAuthor},
+}
+
+// loadSuggestions reads the distinct titles and authors of the books, with
+// the number of books having each, for the suggestion index
+func (r *bookRepository) loadSuggestions() ([]search.Suggestion, error) {
+	var suggestions []search.Suggestion
+	for _, source := range suggestionColumns {
+		var counted []search.Suggestion
+		err := r.DB.Model(&models.Book{}).
+			Select(source.column + " AS text, COUNT(*) AS count").
+			Group(source.column).
+			Find(&counted).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range counted {
+			counted[i].Kind = source.kind
+		}
+		suggestions = append(suggestions, counted...)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/search"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSuggestBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/books/suggest", repo.SuggestBooks)
+
+	// The index is only loaded once, counted by title and by author
+	db := dryRunDB(t)
+	var statements []string
+	db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		rows := []search.Suggestion{{Text: "The Hobbit", Count: 1}, {Text: "The Silmarillion", Count: 1}}
+		if strings.HasPrefix(tx.Statement.SQL.String(), "SELECT author") {
+			rows = []search.Suggestion{{Text: "J. R. R. Tolkien", Count: 2}}
+		}
+		*tx.Statement.Dest.(*[]search.Suggestion) = rows
+	})
+	mockDB.EXPECT().Model(&models.Book{}).DoAndReturn(func(value interface{}) *gorm.DB {
+		return db.Model(value)
+	}).Times(2)
+	mockCache.EXPECT().Get(ctx, gomock.Any()).Return(redis.NewStringResult("", redis.Nil)).Times(2)
+	mockCache.EXPECT().Set(ctx, "books_suggest_tolkein_limit_5", gomock.Any(), time.Minute).Return(redis.NewStatusResult("OK", nil))
+	mockCache.EXPECT().Set(ctx, "books_suggest_hob_limit_10", gomock.Any(), time.Minute).Return(redis.NewStatusResult("OK", nil))
+
+	var response struct {
+		Data []search.Suggestion `json:"data"`
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/books/suggest?q=Tolkein&limit=5", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []search.Suggestion{{Text: "J. R. R. Tolkien", Kind: search.KindAuthor, Count: 2}}, response.Data)
+	assert.Equal(t, []string{
+		`SELECT title AS text, COUNT(*) AS count FROM "books" WHERE "books"."deleted_at" IS NULL GROUP BY "title"`,
+		`SELECT author AS text, COUNT(*) AS count FROM "books" WHERE "books"."deleted_at" IS NULL GROUP BY "author"`,
+	}, statements)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books/suggest?q=HOB", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "The Hobbit", response.Data[0].Text)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/books/suggest?q=%20", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"golang-rest-api-template/pkg/cache"
 	"golang-rest-api-template/pkg/database"
@@ -11,6 +12,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"github.com/go-redis/redis/v8"
@@ -20,6 +22,34 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// dryRunDB returns a gorm handle that builds statements without executing
+// them, for code paths that chain on the *gorm.DB returned by the database.
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	return db
+}
+
+// dryRunDBWithRows is a dry run database whose book queries return books
+func dryRunDBWithRows(t *testing.T, books []models.Book) *gorm.DB {
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(*[]models.Book); ok {
+			*dest = append([]models.Book{}, books...)
+		}
+	})
+	return db
+}
+
+// expectTransaction runs the next transaction on db
+func expectTransaction(mockDB *database.MockDatabase, db *gorm.DB) {
+	mockDB.EXPECT().Transaction(gomock.Any()).DoAndReturn(func(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+		return fc(db)
+	})
+}
+
 func TestNewBookRepository(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -86,7 +116,7 @@ func TestFindBooks(t *testing.T) {
 	}).AnyTimes()
 
 	books := []models.Book{{Title: "Book One", Author: "Author One"}}
-	cachedData, _ := json.Marshal(books)
+	cachedData, _ := json.Marshal(bookPage{Data: books})
 	mockCache.EXPECT().Get(ctx, "books_offset_0_limit_10").Return(redis.NewStringResult(string(cachedData), nil))
 
 	w := httptest.NewRecorder()
@@ -124,15 +154,20 @@ func TestCreateBook(t *testing.T) {
 	}
 
 	// Set up database mock to simulate successful book creation
-	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(book *models.Book) *gorm.DB {
-		// Normally, you might simulate setting an ID or other fields modified by the DB
-		return &gorm.DB{Error: nil}
+	db := dryRunDB(t)
+	var created []interface{}
+	db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		created = append(created, tx.Statement.Dest)
 	})
+	expectTransaction(mockDB, db)
 
 	// Set up cache mock to simulate key retrieval and deletion
 	keyPattern := "books_offset_*"
 	mockCache.EXPECT().Keys(ctx, keyPattern).Return(redis.NewStringSliceResult([]string{"books_offset_0_limit_10"}, nil))
 	mockCache.EXPECT().Del(ctx, "books_offset_0_limit_10").Return(redis.NewIntResult(1, nil))
+	mockCache.EXPECT().Keys(ctx, "books_cursor_*").Return(redis.NewStringSliceResult(nil, nil))
+	mockCache.EXPECT().Keys(ctx, "books_suggest_*").Return(redis.NewStringSliceResult(nil, nil))
+	mockCache.EXPECT().Keys(ctx, "books_facets_*").Return(redis.NewStringSliceResult(nil, nil))
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("POST", "/books", bytes.NewBuffer(requestBody))
@@ -147,6 +182,15 @@ func TestCreateBook(t *testing.T) {
 	// Assertions to check the response
 	assert.Equal(t, http.StatusCreated, w.Code, "Expected HTTP status code 201")
 	assert.Contains(t, w.Body.String(), "New Book", "Response body should contain the book title")
+
+	// The book is created as a new work with its first revision and credited to its byline author
+	assert.Len(t, created, 5)
+	assert.Equal(t, "New Book", created[0].(*models.Work).Title)
+	revision := created[2].(*models.BookRevision)
+	assert.Equal(t, models.RevisionCreate, revision.Action)
+	assert.Equal(t, "author,title", revision.ChangedFields)
+	assert.Equal(t, "newauthor", created[3].(*models.Author).NameKey)
+	assert.Equal(t, models.AuthorRoleAuthor, created[4].(*models.BookAuthor).Role)
 }
 
 func TestFindBook(t *testing.T) {
@@ -222,8 +266,9 @@ func TestDeleteBook(t *testing.T) {
 
 	// Create mock for the database
 	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
 	ctx := context.Background()
-	repo := NewBookRepository(mockDB, nil, &ctx)
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
 
 	// Set up Gin for testing
 	gin.SetMode(gin.TestMode)
@@ -252,10 +297,24 @@ func TestDeleteBook(t *testing.T) {
 			return mockDB
 		}).Times(1)
 
-	// Mock Delete method
-	mockDB.EXPECT().
-		Delete(&existingBook).
-		Return(&gorm.DB{Error: nil}).Times(1)
+	// Delete within a transaction that also records the revision
+	db := dryRunDB(t)
+	var deleteSQL string
+	db.Callback().Delete().After("gorm:delete").Register("test:delete", func(tx *gorm.DB) {
+		deleteSQL = tx.Statement.SQL.String()
+		tx.RowsAffected = 1
+	})
+	expectTransaction(mockDB, db)
+
+	// Cached pages would still list the deleted book
+	for _, pattern := range bookCachePatterns {
+		var keys []string
+		if pattern == "books_offset_*" {
+			keys = []string{"books_offset_0_limit_10"}
+		}
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(keys, nil))
+	}
+	mockCache.EXPECT().Del(ctx, "books_offset_0_limit_10").Return(redis.NewIntResult(1, nil))
 
 	// Mock Error method to return nil
 	mockDB.EXPECT().Error().Return(nil).AnyTimes()
@@ -267,4 +326,5 @@ func TestDeleteBook(t *testing.T) {
 
 	// Assert the response
 	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, `UPDATE "books" SET "deleted_at"=$1 WHERE version = $2 AND "books"."id" = $3 AND "books"."deleted_at" IS NULL`, deleteSQL)
 }
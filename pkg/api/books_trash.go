@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"golang-rest-api-template/pkg/jobs"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// trashed selects deleted books only
+func trashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// TrashedBooks godoc
+// @Summary List deleted books
+// @Description List the books in the trash, most recently deleted first. Trashed books are purged after the retention period
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Book "Deleted books"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient role"
+// @Router /books/trash [get]
+func (r *bookRepository) TrashedBooks(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	books := []models.Book{}
+	if err := r.DB.Scopes(trashed).Order("deleted_at DESC, id").Offset(offset).Limit(limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": books})
+}
+
+// RestoreBook godoc
+// @Summary Restore a deleted book
+// @Description Move the book with the given ID out of the trash
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} models.Book "Restored book"
+// @Header 200 {string} ETag "Entity tag of the restored version"
+// @Failure 403 {string} string "Insufficient role"
+// @Failure 404 {string} string "book not found in trash"
+// @Failure 409 {string} string "Another book has the same ISBN-13"
+// @Router /books/{id}/restore [post]
+func (r *bookRepository) RestoreBook(c *gin.Context) {
+	var book models.Book
+
+	if err := r.DB.Scopes(trashed).Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found in trash"})
+		return
+	}
+
+	// The restored book is a new version, so ETags from before the delete no longer match
+	revision := newRevision(c, models.RevisionRestore)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&book).Unscoped().Where("deleted_at IS NOT NULL").
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+		book.DeletedAt = gorm.DeletedAt{}
+		book.Version++
+		before := editableBook(book)
+		return recordRevision(tx, revision, &before, book)
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another book has the same ISBN-13"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore book"})
+		return
+	}
+
+	r.invalidateBookCache()
+	r.Suggestions.Invalidate()
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusOK, gin.H{"data": book})
+}
+
+// PurgeBook godoc
+// @Summary Permanently delete a book
+// @Description Permanently delete the book with the given ID and its cover from the trash. Books have to be deleted before they can be purged
+// @Tags books
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Param id path string true "Book ID"
+// @Success 204 {string} string "Successfully purged book"
+// @Failure 403 {string} string "Insufficient role"
+// @Failure 404 {string} string "book not found in trash"
+// @Router /books/trash/{id} [delete]
+func (r *bookRepository) PurgeBook(c *gin.Context) {
+	// Caches never hold books in the trash, so purging one leaves them valid
+	purged, err := jobs.PurgeBooks(c.Request.Context(), r.DB.Scopes(trashed).Where("id = ?", c.Param("id")), r.Blobs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge book"})
+		return
+	}
+	if purged == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book not found in trash"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTrashedScope(t *testing.T) {
+	var books []models.Book
+	stmt := dryRunDB(t).Scopes(trashed).Order("deleted_at DESC, id").Find(&books).Statement
+
+	assert.Equal(t, `SELECT * FROM "books" WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`, stmt.SQL.String())
+}
+
+func TestRestoreBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	deletedAt := gorm.DeletedAt{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	var statement *gorm.Statement
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:row", func(tx *gorm.DB) {
+		*tx.Statement.Dest.(*models.Book) = models.Book{ID: 1, Title: "Dune", Version: 2, DeletedAt: deletedAt}
+	})
+	db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+		statement = tx.Statement
+	})
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(db.Scopes)
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/books/:id/restore", repo.RestoreBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books/1/restore", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"deleted_at":null`)
+	assert.Equal(t, `"1-3"`, w.Header().Get("ETag"))
+	assert.Equal(t,
+		`UPDATE "books" SET "deleted_at"=$1,"version"=version + 1,"updated_at"=$2 WHERE deleted_at IS NOT NULL AND "id" = $3`,
+		statement.SQL.String())
+}
+
+func TestPurgeBook(t *testing.T) {
+	for _, test := range []struct {
+		rowsAffected int64
+		status       int
+	}{
+		{1, http.StatusNoContent},
+		{0, http.StatusNotFound},
+	} {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		ctx := context.Background()
+		repo := NewBookRepository(mockDB, nil, &ctx)
+		blobs := storage.NewLocalStore(t.TempDir())
+		repo.Blobs = blobs
+		assert.NoError(t, blobs.Put(ctx, "covers/1/ab/original.jpg", strings.NewReader("cover")))
+
+		var sql string
+		db := dryRunDB(t)
+		db.Callback().Delete().After("gorm:delete").Register("test:statement", func(tx *gorm.DB) {
+			sql = tx.Statement.SQL.String()
+			if test.rowsAffected > 0 {
+				*tx.Statement.Dest.(*[]models.Book) = []models.Book{{ID: 1, CoverKeys: map[string]string{"original": "covers/1/ab/original.jpg"}}}
+			}
+			tx.RowsAffected = test.rowsAffected
+		})
+		mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(db.Scopes)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.DELETE("/books/trash/:id", repo.PurgeBook)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/books/trash/1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, test.status, w.Code)
+		// Only books in the trash are permanently deleted, along with their covers
+		assert.Equal(t, `DELETE FROM "books" WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "id","cover_keys"`, sql)
+		_, _, err := blobs.Open(ctx, "covers/1/ab/original.jpg")
+		assert.Equal(t, test.rowsAffected > 0, errors.Is(err, storage.ErrNotFound))
+		ctrl.Finish()
+	}
+}
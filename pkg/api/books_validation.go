@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"golang-rest-api-template/pkg/isbn"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+// errDuplicateISBN is returned when another book not in the trash has the same ISBN-13
+var errDuplicateISBN = errors.New("isbn13 is already used by another book")
+
+// errUnknownSeries is returned when the series_id of a book names no series
+var errUnknownSeries = errors.New("series_id does not exist")
+
+// bookFieldErrors maps the JSON names of the invalid fields of a book to their problem
+type bookFieldErrors map[string]string
+
+func (e bookFieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for i, field := range fields {
+		fields[i] = field + ": " + e[field]
+	}
+	return strings.Join(fields, "; ")
+}
+
+// bindBook decodes the JSON body of the request into input, a *CreateBook or
+// *UpdateBook, and checks it like decodeBook does for the other write paths
+func bindBook(c *gin.Context, input interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		return err
+	}
+	return decodeBook(data, input)
+}
+
+// checkBook normalizes the ISBNs and language of input, a *CreateBook or
+// *UpdateBook, and validates it. Invalid fields are reported as bookFieldErrors.
+func checkBook(input interface{}) error {
+	var isbn10, isbn13, lang *string
+	var inSeries, hasVolume bool
+	switch input := input.(type) {
+	case *models.CreateBook:
+		isbn10, isbn13, lang = &input.ISBN10, &input.ISBN13, &input.Language
+		inSeries, hasVolume = input.SeriesID != nil, input.SeriesVolume != nil
+	case *models.UpdateBook:
+		isbn10, isbn13, lang = &input.ISBN10, &input.ISBN13, &input.Language
+		inSeries, hasVolume = input.SeriesID != nil, input.SeriesVolume != nil
+	default:
+		return binding.Validator.ValidateStruct(input)
+	}
+
+	problems := bookFieldErrors{}
+	normalizeISBNs(isbn10, isbn13, problems)
+	if tag, err := language.Parse(*lang); *lang != "" && err == nil {
+		*lang = tag.String()
+	}
+	if hasVolume && !inSeries {
+		problems["series_volume"] = "requires series_id"
+	}
+
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		var invalid validator.ValidationErrors
+		if !errors.As(err, &invalid) {
+			return err
+		}
+		for _, fieldError := range invalid {
+			problems[jsonFieldName(input, fieldError.StructField())] = fieldMessage(fieldError)
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// normalizeISBNs cleans up both ISBNs and fills in the one that is missing,
+// adding the problems of invalid ones
+func normalizeISBNs(isbn10, isbn13 *string, problems bookFieldErrors) {
+	if *isbn10 != "" {
+		normalized, err := isbn.Normalize10(*isbn10)
+		if errors.Is(err, isbn.ErrFormat) {
+			problems["isbn10"] = "must have 10 digits, the last one may be X"
+		} else if err != nil {
+			problems["isbn10"] = "has a wrong check digit"
+		}
+		*isbn10 = normalized
+	}
+	if *isbn13 != "" {
+		normalized, err := isbn.Normalize13(*isbn13)
+		if errors.Is(err, isbn.ErrFormat) {
+			problems["isbn13"] = "must have 13 digits starting with 978 or 979"
+		} else if err != nil {
+			problems["isbn13"] = "has a wrong check digit"
+		}
+		*isbn13 = normalized
+	}
+	if len(problems) > 0 {
+		return
+	}
+
+	switch {
+	case *isbn13 == "" && *isbn10 != "":
+		*isbn13 = isbn.To13(*isbn10)
+	case *isbn10 == "" && *isbn13 != "":
+		*isbn10, _ = isbn.To10(*isbn13)
+	case *isbn10 != "" && isbn.To13(*isbn10) != *isbn13:
+		problems["isbn13"] = "does not match isbn10"
+	}
+}
+
+// jsonFieldName returns the JSON name of the field of the struct input points to
+func jsonFieldName(input interface{}, field string) string {
+	if structField, ok := reflect.TypeOf(input).Elem().FieldByName(field); ok {
+		if name := strings.Split(structField.Tag.Get("json"), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field
+}
+
+// fieldMessage describes a failed validation rule
+func fieldMessage(fieldError validator.FieldError) string {
+	unit := ""
+	if fieldError.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldError.Param() + unit
+	case "max":
+		return "must be at most " + fieldError.Param() + unit
+	case "bcp47_language_tag":
+		return "must be a BCP 47 language tag, e.g. en or pt-BR"
+	}
+	return "is invalid"
+}
+
+// translateBookError turns the unique violation of the ISBN-13 index into errDuplicateISBN
+func translateBookError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errDuplicateISBN
+	}
+	return err
+}
+
+// rejectedBook returns the status and field problems of a book that passed
+// checkBook but conflicts with the stored data, or nil problems if err is
+// not such a conflict
+func rejectedBook(err error) (int, bookFieldErrors) {
+	switch {
+	case errors.Is(err, errDuplicateISBN):
+		return http.StatusConflict, bookFieldErrors{"isbn13": "is already used by another book"}
+	case errors.Is(err, errUnknownSeries):
+		return http.StatusUnprocessableEntity, bookFieldErrors{"series_id": "does not exist"}
+	}
+	return 0, nil
+}
+
+// writeBookError responds to an invalid book, listing the problem of each field
+func writeBookError(c *gin.Context, status int, err error) {
+	var problems bookFieldErrors
+	if errors.As(err, &problems) {
+		c.JSON(status, gin.H{"error": "Invalid book", "fields": problems})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
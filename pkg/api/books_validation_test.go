@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCheckBook(t *testing.T) {
+	// Either ISBN fills in the other and the language tag is canonicalized
+	input := models.CreateBook{Title: "Dune", Author: "Frank Herbert", ISBN10: "0-441-17271-7", Language: "EN-us"}
+	assert.NoError(t, checkBook(&input))
+	assert.Equal(t, "0441172717", input.ISBN10)
+	assert.Equal(t, "9780441172719", input.ISBN13)
+	assert.Equal(t, "en-US", input.Language)
+
+	update := models.UpdateBook{Title: "Dune", Author: "Frank Herbert", ISBN13: "978-0-441-17271-9"}
+	assert.NoError(t, checkBook(&update))
+	assert.Equal(t, "0441172717", update.ISBN10)
+
+	// ISBN-13s with the 979 prefix have no ISBN-10
+	update = models.UpdateBook{Title: "Dune", Author: "Frank Herbert", ISBN13: "9791090636071"}
+	assert.NoError(t, checkBook(&update))
+	assert.Equal(t, "", update.ISBN10)
+
+	input = models.CreateBook{Author: "Frank Herbert", ISBN10: "0441172718", ISBN13: "97804411727", Language: "Klingon", PageCount: -1, Edition: strings.Repeat("x", 101)}
+	assert.Equal(t, bookFieldErrors{
+		"title":      "is required",
+		"isbn10":     "has a wrong check digit",
+		"isbn13":     "must have 13 digits starting with 978 or 979",
+		"language":   "must be a BCP 47 language tag, e.g. en or pt-BR",
+		"page_count": "must be at least 1",
+		"edition":    "must be at most 100 characters",
+	}, checkBook(&input))
+
+	input = models.CreateBook{Title: "Dune", Author: "Frank Herbert", ISBN10: "0441172717", ISBN13: "9780306406157"}
+	assert.EqualError(t, checkBook(&input), "isbn13: does not match isbn10")
+
+	volume := 1.5
+	input = models.CreateBook{Title: "Dune", Author: "Frank Herbert", SeriesVolume: &volume}
+	assert.EqualError(t, checkBook(&input), "series_volume: requires series_id")
+}
+
+func TestCreateBookFieldErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewBookRepository(database.NewMockDatabase(ctrl), nil, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) { c.Set("appCtx", repo) })
+	r.POST("/books", repo.CreateBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"title": "Dune", "author": "Frank Herbert", "isbn13": "9780441172710"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "Invalid book", "fields": {"isbn13": "has a wrong check digit"}}`, w.Body.String())
+
+	// Unknown fields are rejected like on every other write path
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"title": "Dune", "author": "Frank Herbert", "pages": 412}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown field \"pages\"`)
+}
+
+func TestCreateBookDuplicateISBN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	// The unique index on isbn13 rejects the book
+	db := dryRunDB(t)
+	db.Callback().Create().After("gorm:create").Register("test:duplicate", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Book); ok {
+			tx.AddError(gorm.ErrDuplicatedKey)
+		}
+	})
+	expectTransaction(mockDB, db)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) { c.Set("appCtx", repo) })
+	r.POST("/books", repo.CreateBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"title": "Dune", "author": "Frank Herbert", "isbn10": "0441172717"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var response struct {
+		Fields bookFieldErrors `json:"fields"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, bookFieldErrors{"isbn13": "is already used by another book"}, response.Fields)
+}
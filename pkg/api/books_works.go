@@ -0,0 +1,270 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Reasons a split is rejected
+var (
+	errNotEditions = errors.New("Books must all be editions of the work")
+	errEmptyWork   = errors.New("At least one edition must stay with the work")
+)
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// moveEditions links the books matching the conditions to the work with ID
+// workID. Their version is bumped so that cached copies are revalidated;
+// editions in the trash move too.
+func moveEditions(tx *gorm.DB, workID uint, query interface{}, args ...interface{}) error {
+	return tx.Model(&models.Book{}).Unscoped().Where(query, args...).
+		Updates(map[string]interface{}{"work_id": workID, "version": gorm.Expr("version + 1")}).Error
+}
+
+// FindWork godoc
+// @Summary Find a work by ID
+// @Tags works
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Work ID"
+// @Success 200 {object} models.Work "Work"
+// @Failure 404 {string} string "work not found"
+// @Router /works/{id} [get]
+func (r *bookRepository) FindWork(c *gin.Context) {
+	var work models.Work
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&work).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": work})
+}
+
+// WorkEditions godoc
+// @Summary List the editions of a work
+// @Description List the books grouped into a work, oldest first. Books in the trash are left out
+// @Tags works
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Work ID"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Book "Editions"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "work not found"
+// @Router /works/{id}/editions [get]
+func (r *bookRepository) WorkEditions(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var work models.Work
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&work).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work not found"})
+		return
+	}
+
+	books := []models.Book{}
+	err = r.DB.Model(&models.Book{}).
+		Where("work_id = ?", work.ID).
+		Order("published_at, id").
+		Offset(offset).Limit(limit).
+		Find(&books).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch editions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": books})
+}
+
+// UpdateWork godoc
+// @Summary Rename a work by ID
+// @Description Change the title of a work, the titles of its editions are left as they are (librarians and admins only)
+// @Tags works
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Param input body models.UpdateWork true "Work"
+// @Success 200 {object} models.Work "Updated work"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "work not found"
+// @Router /works/{id} [put]
+func (r *bookRepository) UpdateWork(c *gin.Context) {
+	var work models.Work
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&work).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work not found"})
+		return
+	}
+
+	var input models.UpdateWork
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := r.DB.Model(&work).Updates(models.Work{Title: strings.TrimSpace(input.Title)}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update work"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": work})
+}
+
+// MergeWorks godoc
+// @Summary Merge works into a work
+// @Description Move all editions of the given works, including those in the trash, into the work and delete the given works (librarians and admins only)
+// @Tags works
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the work that is kept"
+// @Param input body models.MergeWorks true "Works to merge"
+// @Success 200 {object} models.Work "Merged work"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "work not found"
+// @Failure 422 {string} string "Unknown works or the work itself"
+// @Router /works/{id}/merge [post]
+func (r *bookRepository) MergeWorks(c *gin.Context) {
+	var work models.Work
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&work).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work not found"})
+		return
+	}
+
+	var input models.MergeWorks
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ids := uniqueIDs(input.WorkIDs)
+	for _, id := range ids {
+		if id == work.ID {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A work cannot be merged into itself"})
+			return
+		}
+	}
+
+	var merged []models.Work
+	if err := r.DB.Where("id IN ?", ids).Find(&merged).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch works"})
+		return
+	}
+	if len(merged) != len(ids) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Some works to merge do not exist"})
+		return
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveEditions(tx, work.ID, "work_id IN ?", ids); err != nil {
+			return err
+		}
+		return tx.Delete(&models.Work{}, ids).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge works"})
+		return
+	}
+	r.invalidateBookCache()
+
+	c.JSON(http.StatusOK, gin.H{"data": work})
+}
+
+// SplitWork godoc
+// @Summary Split editions off a work
+// @Description Move some editions of a work into a new work. At least one edition stays with the work (librarians and admins only)
+// @Tags works
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Param input body models.SplitWork true "Editions to split off"
+// @Success 201 {object} models.Work "New work"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "work not found"
+// @Failure 422 {string} string "The books are not editions of the work or would be all of them"
+// @Router /works/{id}/split [post]
+func (r *bookRepository) SplitWork(c *gin.Context) {
+	var work models.Work
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&work).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work not found"})
+		return
+	}
+
+	var input models.SplitWork
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ids := uniqueIDs(input.BookIDs)
+
+	split := models.Work{Title: strings.TrimSpace(input.Title)}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var editions []models.Book
+		if err := tx.Unscoped().Where("work_id = ?", work.ID).Order("id").Find(&editions).Error; err != nil {
+			return err
+		}
+		titles := make(map[uint]string, len(editions))
+		for _, edition := range editions {
+			titles[edition.ID] = edition.Title
+		}
+		for _, id := range ids {
+			if _, ok := titles[id]; !ok {
+				return fmt.Errorf("%w, %d is not", errNotEditions, id)
+			}
+		}
+		if len(ids) == len(editions) {
+			return errEmptyWork
+		}
+
+		if split.Title == "" {
+			split.Title = titles[ids[0]]
+		}
+		if err := tx.Create(&split).Error; err != nil {
+			return err
+		}
+		return moveEditions(tx, split.ID, "id IN ?", ids)
+	})
+	if errors.Is(err, errNotEditions) || errors.Is(err, errEmptyWork) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to split work"})
+		return
+	}
+	r.invalidateBookCache()
+
+	c.JSON(http.StatusCreated, gin.H{"data": split})
+}
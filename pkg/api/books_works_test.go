@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// expectWork makes the next lookup by ID load work
+func expectWork(mockDB *database.MockDatabase, id string, work models.Work) {
+	mockDB.EXPECT().Where("id = ?", id).Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Work) = work
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+}
+
+func TestMergeWorks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+	expectWork(mockDB, "1", models.Work{ID: 1, Title: "The Hobbit"})
+	mockDB.EXPECT().Where("id IN ?", []uint{2, 3}).Return(mockDB)
+	mockDB.EXPECT().Find(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) *gorm.DB {
+		*dest.(*[]models.Work) = []models.Work{{ID: 2, Title: "Der Hobbit"}, {ID: 3, Title: "Bilbo le Hobbit"}}
+		return dryRunDB(t)
+	})
+
+	var statements []string
+	db := dryRunDB(t)
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}
+	db.Callback().Update().After("gorm:update").Register("test:statement", record)
+	db.Callback().Delete().After("gorm:delete").Register("test:statement", record)
+	expectTransaction(mockDB, db)
+	for _, pattern := range bookCachePatterns {
+		mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/works/:id/merge", repo.MergeWorks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/works/1/merge", strings.NewReader(`{"work_ids": [2, 3, 2]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// Editions in the trash move too
+	assert.Equal(t, []string{
+		`UPDATE "books" SET "version"=version + 1,"work_id"=$1,"updated_at"=$2 WHERE work_id IN ($3,$4)`,
+		`DELETE FROM "works" WHERE "works"."id" IN ($1,$2)`,
+	}, statements)
+}
+
+func TestMergeWorkIntoItself(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	expectWork(mockDB, "1", models.Work{ID: 1, Title: "The Hobbit"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/works/:id/merge", repo.MergeWorks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/works/1/merge", strings.NewReader(`{"work_ids": [2, 1]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestSplitWork(t *testing.T) {
+	for _, test := range []struct {
+		body   string
+		status int
+		error  string
+	}{
+		{`{"book_ids": [11]}`, http.StatusCreated, ""},
+		{`{"book_ids": [10, 11]}`, http.StatusUnprocessableEntity, "At least one edition must stay with the work"},
+		{`{"book_ids": [12]}`, http.StatusUnprocessableEntity, "Books must all be editions of the work, 12 is not"},
+	} {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		mockCache := cache.NewMockCache(ctrl)
+		ctx := context.Background()
+		repo := NewBookRepository(mockDB, mockCache, &ctx)
+
+		expectWork(mockDB, "1", models.Work{ID: 1, Title: "The Hobbit"})
+		var created *models.Work
+		db := dryRunDB(t)
+		db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+			*tx.Statement.Dest.(*[]models.Book) = []models.Book{{ID: 10, Title: "The Hobbit"}, {ID: 11, Title: "Der Hobbit"}}
+		})
+		db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+			created = tx.Statement.Dest.(*models.Work)
+		})
+		expectTransaction(mockDB, db)
+		if test.status == http.StatusCreated {
+			for _, pattern := range bookCachePatterns {
+				mockCache.EXPECT().Keys(ctx, pattern).Return(redis.NewStringSliceResult(nil, nil))
+			}
+		}
+
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+		r.POST("/works/:id/split", repo.SplitWork)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/works/1/split", strings.NewReader(test.body))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, test.status, w.Code, test.body)
+		if test.error != "" {
+			assert.JSONEq(t, `{"error": "`+test.error+`"}`, w.Body.String())
+		} else {
+			// The new work is named after the first book split off
+			assert.Equal(t, "Der Hobbit", created.Title)
+		}
+		ctrl.Finish()
+	}
+}
@@ -0,0 +1,383 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportProgressRows is how many rows an export job writes between progress updates
+const exportProgressRows = 1000
+
+// exportStaleAfter is how long a running job may go without a heartbeat
+// before another worker takes it over, e.g. after a crash
+const exportStaleAfter = 5 * time.Minute
+
+// exportHeartbeat is how often a worker touches the job it runs
+const exportHeartbeat = time.Minute
+
+// defaultExportTTLHours is how long export files are kept if EXPORT_TTL_HOURS is not set
+const defaultExportTTLHours = 24
+
+type ExportRepository interface {
+	CreateExport(c *gin.Context)
+	FindExport(c *gin.Context)
+	DownloadExport(c *gin.Context)
+}
+
+// exportRepository runs book exports in the background and serves their files
+type exportRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+	// Dir is where export files are written
+	Dir string
+	// TTL is how long export files are kept once the job is done
+	TTL time.Duration
+	// wake signals the worker that a job was queued
+	wake chan struct{}
+}
+
+func NewExportRepository(db database.Database, ctx *context.Context) *exportRepository {
+	return &exportRepository{
+		DB:   db,
+		Ctx:  ctx,
+		Dir:  exportDir(),
+		TTL:  exportTTL(),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// exportDir reads the directory of export files from EXPORT_DIR
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "book-exports")
+}
+
+// exportTTL reads how long export files are kept from EXPORT_TTL_HOURS
+func exportTTL() time.Duration {
+	hours := defaultExportTTLHours
+	if value := os.Getenv("EXPORT_TTL_HOURS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			log.Printf("Invalid EXPORT_TTL_HOURS %q, keeping exports for %d hours", value, hours)
+		} else {
+			hours = parsed
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// CreateExport godoc
+// @Summary Start an export of books
+// @Description Export all books matching the filters of the list endpoint to a file in the background. Poll the returned job for its progress and download link
+// @Tags exports
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param format query string false "Export format (csv, ndjson, json)" default(csv)
+// @Param author query string false "Author, case insensitive"
+// @Param title query string false "Text the title contains, case insensitive"
+// @Param genre query string false "Genre, case insensitive"
+// @Param language query string false "Language, case insensitive"
+// @Param decade query int false "Decade of publication, e.g. 1990"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_after query string false "Updated at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_before query string false "Updated before (RFC 3339 or YYYY-MM-DD)"
+// @Param ids query string false "Comma separated list of book IDs"
+// @Param publisher_id query int false "ID of the publisher books are linked to"
+// @Param series_id query int false "ID of the series books belong to"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending (id, title, author, created_at, updated_at)" default(id)
+// @Success 202 {object} models.ExportJob "Queued export job"
+// @Header 202 {string} Location "URL of the export job"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /exports [post]
+func (r *exportRepository) CreateExport(c *gin.Context) {
+	format := c.DefaultQuery("format", exportCSV)
+	if _, ok := exportContentTypes[format]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected csv, ndjson or json"})
+		return
+	}
+	query, err := parseBookQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job := models.ExportJob{
+		ID:     auth.RandomHex(16),
+		Status: models.ExportPending,
+		Format: format,
+		Query:  query.normalized(),
+		Owner:  actor(c),
+	}
+	if err := r.DB.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export"})
+		return
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
+
+// FindExport godoc
+// @Summary Get an export job
+// @Description Get the status and progress of an export job, with the download link once it is completed
+// @Tags exports
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce json
+// @Param id path string true "Export job ID"
+// @Success 200 {object} models.ExportJob "Export job"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "export not found"
+// @Router /exports/{id} [get]
+func (r *exportRepository) FindExport(c *gin.Context) {
+	job, ok := r.ownExport(c)
+	if !ok {
+		return
+	}
+	if job.Status == models.ExportCompleted {
+		job.Download = c.Request.URL.Path + "/download"
+	}
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// DownloadExport godoc
+// @Summary Download an export
+// @Description Download the file of a completed export job
+// @Tags exports
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param id path string true "Export job ID"
+// @Success 200 {file} file "Export file"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "export not found"
+// @Failure 409 {string} string "export is not completed"
+// @Failure 410 {string} string "export has expired"
+// @Router /exports/{id}/download [get]
+func (r *exportRepository) DownloadExport(c *gin.Context) {
+	job, ok := r.ownExport(c)
+	if !ok {
+		return
+	}
+	if job.Status != models.ExportCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "export is not completed"})
+		return
+	}
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "export has expired"})
+		return
+	}
+
+	file, err := os.Open(job.Path)
+	if err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "export has expired"})
+		return
+	}
+	defer file.Close()
+	c.Header("Content-Disposition", `attachment; filename="books-`+job.ID+`.`+job.Format+`"`)
+	c.DataFromReader(http.StatusOK, job.Size, exportContentTypes[job.Format], file, nil)
+}
+
+// ownExport loads the export job of the request, which only its owner may see
+func (r *exportRepository) ownExport(c *gin.Context) (models.ExportJob, bool) {
+	var job models.ExportJob
+	if err := r.DB.Where("id = ? AND owner = ?", c.Param("id"), actor(c)).First(&job).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "export not found"})
+		return job, false
+	}
+	return job, true
+}
+
+// StartWorker runs queued export jobs one after the other until ctx is done.
+// Other instances sharing the database may run workers too.
+func (r *exportRepository) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for {
+				ran, err := r.runNextExport(ctx)
+				if err != nil {
+					log.Printf("Failed to run export: %v", err)
+				}
+				if !ran {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runNextExport claims the oldest pending or stale job and runs it. It
+// reports whether there was a job to run.
+func (r *exportRepository) runNextExport(ctx context.Context) (bool, error) {
+	var job models.ExportJob
+	stale := time.Now().Add(-exportStaleAfter)
+	err := r.DB.Where("status = ? OR (status = ? AND updated_at < ?)", models.ExportPending, models.ExportRunning, stale).Order("created_at").First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Only one worker wins the job, the others see it changed
+	claim := auth.RandomHex(16)
+	claimed := r.DB.Model(&job).Where("status = ? AND updated_at = ?", job.Status, job.UpdatedAt).
+		Updates(map[string]interface{}{"status": models.ExportRunning, "processed": 0, "claim": claim})
+	if claimed.Error != nil {
+		return false, claimed.Error
+	}
+	if claimed.RowsAffected == 0 {
+		return true, nil
+	}
+	job.Claim = claim
+
+	runCtx, cancel := context.WithCancel(ctx)
+	go r.heartbeat(runCtx, cancel, job)
+	err = r.runExport(runCtx, &job)
+	cancel()
+
+	expires := time.Now().Add(r.TTL)
+	updates := map[string]interface{}{"expires_at": expires}
+	if err != nil {
+		updates["status"] = models.ExportFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = models.ExportCompleted
+		updates["completed_at"] = time.Now()
+		updates["path"] = job.Path
+		updates["size"] = job.Size
+		updates["processed"] = job.Processed
+	}
+	// A worker that took the job over in the meantime owns it now
+	result := r.DB.Model(&job).Where("status = ? AND claim = ?", models.ExportRunning, claim).Updates(updates)
+	if (result.Error != nil || result.RowsAffected == 0) && job.Path != "" {
+		os.Remove(job.Path)
+	}
+	return true, result.Error
+}
+
+// heartbeat touches job every exportHeartbeat until ctx is done, so that it
+// does not look stale however long it runs. It cancels the run once another
+// worker has claimed the job.
+func (r *exportRepository) heartbeat(ctx context.Context, cancel context.CancelFunc, job models.ExportJob) {
+	ticker := time.NewTicker(exportHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := r.DB.Model(&models.ExportJob{}).Where("id = ? AND claim = ?", job.ID, job.Claim).Update("updated_at", time.Now())
+		if result.Error != nil {
+			log.Printf("Failed to update export %s: %v", job.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			cancel()
+			return
+		}
+	}
+}
+
+// runExport writes the file of job. Each run writes a file of its own, which
+// is removed if the run fails and only becomes visible once the job records it.
+func (r *exportRepository) runExport(ctx context.Context, job *models.ExportJob) error {
+	values, err := url.ParseQuery(job.Query)
+	if err != nil {
+		return err
+	}
+	query, err := parseBookValues(values)
+	if err != nil {
+		return err
+	}
+
+	if err := r.DB.Model(&models.Book{}).Scopes(query.filter).Count(&job.Total).Error; err != nil {
+		return err
+	}
+	if err := r.DB.Model(job).Where("claim = ?", job.Claim).Update("total", job.Total).Error; err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.Dir, 0o700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(r.Dir, job.ID+"-*."+job.Format)
+	if err != nil {
+		return err
+	}
+	complete := false
+	defer func() {
+		if !complete {
+			os.Remove(file.Name())
+		}
+	}()
+	defer file.Close()
+
+	db := r.DB.Scopes(query.filter, query.order).WithContext(ctx).Model(&models.Book{})
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	buffered := bufio.NewWriter(file)
+	err = writeExport(db, rows, newExportEncoder(job.Format, buffered), func(count int64) error {
+		job.Processed = count
+		if count%exportProgressRows != 0 {
+			return nil
+		}
+		return r.DB.Model(job).Where("claim = ?", job.Claim).Update("processed", count).Error
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	complete = true
+	job.Path, job.Size = file.Name(), info.Size()
+	return nil
+}
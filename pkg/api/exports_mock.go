@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/exports.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockExportRepository is a mock of ExportRepository interface.
+type MockExportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockExportRepositoryMockRecorder
+}
+
+// MockExportRepositoryMockRecorder is the mock recorder for MockExportRepository.
+type MockExportRepositoryMockRecorder struct {
+	mock *MockExportRepository
+}
+
+// NewMockExportRepository creates a new mock instance.
+func NewMockExportRepository(ctrl *gomock.Controller) *MockExportRepository {
+	mock := &MockExportRepository{ctrl: ctrl}
+	mock.recorder = &MockExportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExportRepository) EXPECT() *MockExportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateExport mocks base method.
+func (m *MockExportRepository) CreateExport(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateExport", c)
+}
+
+// CreateExport indicates an expected call of CreateExport.
+func (mr *MockExportRepositoryMockRecorder) CreateExport(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExport", reflect.TypeOf((*MockExportRepository)(nil).CreateExport), c)
+}
+
+// DownloadExport mocks base method.
+func (m *MockExportRepository) DownloadExport(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DownloadExport", c)
+}
+
+// DownloadExport indicates an expected call of DownloadExport.
+func (mr *MockExportRepositoryMockRecorder) DownloadExport(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadExport", reflect.TypeOf((*MockExportRepository)(nil).DownloadExport), c)
+}
+
+// FindExport mocks base method.
+func (m *MockExportRepository) FindExport(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindExport", c)
+}
+
+// FindExport indicates an expected call of FindExport.
+func (mr *MockExportRepositoryMockRecorder) FindExport(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindExport", reflect.TypeOf((*MockExportRepository)(nil).FindExport), c)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestExportTTL(t *testing.T) {
+	t.Setenv("EXPORT_TTL_HOURS", "")
+	assert.Equal(t, 24*time.Hour, exportTTL())
+
+	t.Setenv("EXPORT_TTL_HOURS", "2")
+	assert.Equal(t, 2*time.Hour, exportTTL())
+
+	t.Setenv("EXPORT_TTL_HOURS", "0")
+	assert.Equal(t, 24*time.Hour, exportTTL())
+}
+
+func TestCreateExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewExportRepository(mockDB, &ctx)
+
+	var job *models.ExportJob
+	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(value interface{}) *gorm.DB {
+		job = value.(*models.ExportJob)
+		return &gorm.DB{}
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/exports", func(c *gin.Context) {
+		c.Set("username", "alice")
+		repo.CreateExport(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/exports?format=ndjson&genre=SF&sort=-title", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/exports/"+job.ID, w.Header().Get("Location"))
+	assert.Equal(t, models.ExportPending, job.Status)
+	assert.Equal(t, "ndjson", job.Format)
+	assert.Equal(t, "genre=sf&sort=-title%2Cid", job.Query)
+	assert.Equal(t, "alice", job.Owner)
+	assert.Len(t, repo.wake, 1)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/exports?format=xlsx", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFindExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewExportRepository(mockDB, &ctx)
+
+	// Jobs are only found for their owner
+	mockDB.EXPECT().Where("id = ? AND owner = ?", "abc", "alice").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.ExportJob) = models.ExportJob{ID: "abc", Status: models.ExportCompleted, Total: 2, Processed: 2}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/exports/:id", func(c *gin.Context) {
+		c.Set("username", "alice")
+		repo.FindExport(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/exports/abc", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.ExportJob `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "/exports/abc/download", response.Data.Download)
+}
+
+func TestRunExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewExportRepository(mockDB, &ctx)
+	repo.Dir = t.TempDir()
+
+	// Two books match, the count is stored before the file is written
+	counting := dryRunDB(t)
+	counting.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		*tx.Statement.Dest.(*int64) = 2
+		tx.RowsAffected = 1
+	})
+	mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(func(model interface{}) *gorm.DB {
+		if _, ok := model.(*models.Book); ok {
+			return counting.Model(model)
+		}
+		return dryRunDB(t).Model(model)
+	}).Times(2)
+	db, connector := exportTestDB(t)
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(func(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+		return db.Scopes(funcs...)
+	})
+
+	job := models.ExportJob{ID: "abc", Format: exportCSV, Query: "genre=sf"}
+	assert.NoError(t, repo.runExport(ctx, &job))
+
+	assert.Equal(t, []string{`SELECT * FROM "books" WHERE LOWER(genre) = $1 AND "books"."deleted_at" IS NULL ORDER BY id`}, connector.queries)
+	assert.Equal(t, repo.Dir, filepath.Dir(job.Path))
+	assert.True(t, strings.HasPrefix(filepath.Base(job.Path), "abc-"), job.Path)
+	assert.Equal(t, ".csv", filepath.Ext(job.Path))
+	assert.Equal(t, int64(2), job.Total)
+	assert.Equal(t, int64(2), job.Processed)
+	content, err := os.ReadFile(job.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), job.Size)
+	assert.Contains(t, string(content), "\n2,Dune,Frank Herbert,SF,en,")
+	files, err := os.ReadDir(repo.Dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestRunNextExportTakenOver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewExportRepository(mockDB, &ctx)
+	repo.Dir = t.TempDir()
+
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.ExportJob:
+			*dest = models.ExportJob{ID: "abc", Status: models.ExportPending, Format: exportCSV}
+		case *int64:
+			*dest = 2
+		}
+		tx.RowsAffected = 1
+	})
+	// Another worker claims the job while it runs, so only the first update of
+	// this run, its own claim, finds the job
+	var updates []string
+	db.Callback().Update().After("gorm:update").Register("test:updates", func(tx *gorm.DB) {
+		updates = append(updates, tx.Statement.SQL.String())
+		if len(updates) == 1 {
+			tx.RowsAffected = 1
+		}
+	})
+	mockDB.EXPECT().Where(gomock.Any(), gomock.Any()).Return(mockDB)
+	mockDB.EXPECT().Order("created_at").DoAndReturn(db.Order)
+	mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(db.Model).AnyTimes()
+	rows, _ := exportTestDB(t)
+	mockDB.EXPECT().Scopes(gomock.Any()).DoAndReturn(rows.Scopes)
+
+	ran, err := repo.runNextExport(ctx)
+
+	assert.True(t, ran)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, updates) {
+		assert.Contains(t, updates[0], `"claim"=`)
+		assert.Contains(t, updates[len(updates)-1], `WHERE (status = $`)
+		assert.Contains(t, updates[len(updates)-1], `AND claim = $`)
+	}
+	// The file of the run that lost the job is removed
+	files, err := os.ReadDir(repo.Dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthRepository interface {
+	CreateClientHandler(c *gin.Context)
+	ListClientsHandler(c *gin.Context)
+	RevokeClientHandler(c *gin.Context)
+	TokenHandler(c *gin.Context)
+	IntrospectHandler(c *gin.Context)
+}
+
+// oauthRepository holds shared resources for the OAuth2 authorization server
+type oauthRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+}
+
+func NewOAuthRepository(db database.Database, ctx *context.Context) *oauthRepository {
+	return &oauthRepository{
+		DB:  db,
+		Ctx: ctx,
+	}
+}
+
+// CreateClientHandler godoc
+// @Summary Register an OAuth2 client
+// @Description Registers a machine client for the client credentials grant. The client secret is only returned once (admins only).
+// @Tags oauth
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param   input     body   models.CreateOAuthClient   true   "OAuth2 client object"
+// @Success 201 {object} models.OAuthClient "Successfully registered client"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /oauth/clients [post]
+func (r *oauthRepository) CreateClientHandler(c *gin.Context) {
+	// Scope limited credentials must not be able to register clients
+	if _, limited := c.Get("scopes"); limited {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	user, err := currentUser(c, r.DB)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input models.CreateOAuthClient
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+
+	clientID, clientSecret := auth.GenerateClientCredentials()
+	client := models.OAuthClient{
+		ClientID:   clientID,
+		SecretHash: auth.HashToken(clientSecret),
+		Name:       input.Name,
+		Scopes:     strings.Join(input.Scopes, " "),
+		UserID:     user.ID,
+	}
+	if err := r.DB.Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": client, "client_secret": clientSecret})
+}
+
+// ListClientsHandler godoc
+// @Summary List OAuth2 clients
+// @Description Lists the registered machine clients, revoked ones included (admins only)
+// @Tags oauth
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Success 200 {array} models.OAuthClient "Successfully retrieved clients"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /oauth/clients [get]
+func (r *oauthRepository) ListClientsHandler(c *gin.Context) {
+	var clients []models.OAuthClient
+
+	if err := r.DB.Order("id").Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": clients})
+}
+
+// RevokeClientHandler godoc
+// @Summary Revoke an OAuth2 client
+// @Description Revokes a machine client. It can no longer get tokens and the tokens it holds are rejected (admins only)
+// @Tags oauth
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Client ID"
+// @Success 204 {string} string "Successfully revoked client"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "client not found"
+// @Router /oauth/clients/{id} [delete]
+func (r *oauthRepository) RevokeClientHandler(c *gin.Context) {
+	var client models.OAuthClient
+
+	if err := r.DB.Where("client_id = ?", c.Param("id")).First(&client).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+		return
+	}
+
+	if client.RevokedAt == nil {
+		if err := r.DB.Model(&client).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke client"})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TokenHandler godoc
+// @Summary Issue an access token
+// @Description Issues a scope limited access token using the OAuth2 client credentials grant (RFC 6749, section 4.4). Clients authenticate with HTTP Basic or client_id and client_secret form fields.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param scope formData string false "Space separated list of requested scopes"
+// @Success 200 {string} string "Access token"
+// @Failure 400 {string} string "invalid_request, unsupported_grant_type or invalid_scope"
+// @Failure 401 {string} string "invalid_client"
+// @Router /oauth/token [post]
+func (r *oauthRepository) TokenHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := r.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	if c.PostForm("grant_type") != "client_credentials" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Without an explicit request the client gets every scope it is allowed
+	scopes := strings.Fields(client.Scopes)
+	if requested := strings.Fields(c.PostForm("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !auth.HasScope(client.Scopes, scope) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_scope"})
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	token, err := auth.GenerateClientToken(client.ClientID, scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(auth.ClientTokenTTL.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// IntrospectHandler godoc
+// @Summary Introspect an access token
+// @Description Returns the state of an access token as defined by RFC 7662. Callers authenticate with their client credentials.
+// @Tags oauth
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "Token to introspect"
+// @Success 200 {string} string "Token metadata"
+// @Failure 400 {string} string "invalid_request"
+// @Failure 401 {string} string "invalid_client"
+// @Router /oauth/introspect [post]
+func (r *oauthRepository) IntrospectHandler(c *gin.Context) {
+	if _, ok := r.authenticateClient(c); !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"exp":        claims.ExpiresAt,
+	}
+	if claims.IssuedAt != 0 {
+		response["iat"] = claims.IssuedAt
+	}
+	if claims.ClientID != "" {
+		response["client_id"] = claims.ClientID
+		response["sub"] = claims.Subject
+		response["scope"] = claims.Scope
+	} else {
+		response["username"] = claims.Username
+		response["sub"] = claims.Username
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// authenticateClient verifies the client credentials sent with HTTP Basic or
+// in the form body, writing an invalid_client response when they don't match.
+func (r *oauthRepository) authenticateClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	var client models.OAuthClient
+	if clientID != "" && clientSecret != "" {
+		err := r.DB.Where("client_id = ?", clientID).First(&client).Error()
+		if err == nil && client.RevokedAt == nil && subtle.ConstantTimeCompare([]byte(auth.HashToken(clientSecret)), []byte(client.SecretHash)) == 1 {
+			return &client, true
+		}
+	}
+
+	c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	return nil, false
+}
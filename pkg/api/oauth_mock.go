@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/oauth.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOAuthRepository is a mock of OAuthRepository interface.
+type MockOAuthRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthRepositoryMockRecorder
+}
+
+// MockOAuthRepositoryMockRecorder is the mock recorder for MockOAuthRepository.
+type MockOAuthRepositoryMockRecorder struct {
+	mock *MockOAuthRepository
+}
+
+// NewMockOAuthRepository creates a new mock instance.
+func NewMockOAuthRepository(ctrl *gomock.Controller) *MockOAuthRepository {
+	mock := &MockOAuthRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuthRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthRepository) EXPECT() *MockOAuthRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateClientHandler mocks base method.
+func (m *MockOAuthRepository) CreateClientHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateClientHandler", c)
+}
+
+// CreateClientHandler indicates an expected call of CreateClientHandler.
+func (mr *MockOAuthRepositoryMockRecorder) CreateClientHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClientHandler", reflect.TypeOf((*MockOAuthRepository)(nil).CreateClientHandler), c)
+}
+
+// IntrospectHandler mocks base method.
+func (m *MockOAuthRepository) IntrospectHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "IntrospectHandler", c)
+}
+
+// IntrospectHandler indicates an expected call of IntrospectHandler.
+func (mr *MockOAuthRepositoryMockRecorder) IntrospectHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectHandler", reflect.TypeOf((*MockOAuthRepository)(nil).IntrospectHandler), c)
+}
+
+// ListClientsHandler mocks base method.
+func (m *MockOAuthRepository) ListClientsHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListClientsHandler", c)
+}
+
+// ListClientsHandler indicates an expected call of ListClientsHandler.
+func (mr *MockOAuthRepositoryMockRecorder) ListClientsHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClientsHandler", reflect.TypeOf((*MockOAuthRepository)(nil).ListClientsHandler), c)
+}
+
+// RevokeClientHandler mocks base method.
+func (m *MockOAuthRepository) RevokeClientHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RevokeClientHandler", c)
+}
+
+// RevokeClientHandler indicates an expected call of RevokeClientHandler.
+func (mr *MockOAuthRepositoryMockRecorder) RevokeClientHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeClientHandler", reflect.TypeOf((*MockOAuthRepository)(nil).RevokeClientHandler), c)
+}
+
+// TokenHandler mocks base method.
+func (m *MockOAuthRepository) TokenHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TokenHandler", c)
+}
+
+// TokenHandler indicates an expected call of TokenHandler.
+func (mr *MockOAuthRepositoryMockRecorder) TokenHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenHandler", reflect.TypeOf((*MockOAuthRepository)(nil).TokenHandler), c)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newOAuthTestRouter(t *testing.T, client models.OAuthClient) (*gin.Engine, *gomock.Controller) {
+	ctrl := gomock.NewController(t)
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOAuthRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("client_id = ?", client.ClientID).Return(mockDB).AnyTimes()
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.OAuthClient) = client
+		return mockDB
+	}).AnyTimes()
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/oauth/token", repo.TokenHandler)
+	r.POST("/oauth/introspect", repo.IntrospectHandler)
+	return r, ctrl
+}
+
+func postForm(r *gin.Engine, path string, form url.Values, clientID, clientSecret string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTokenHandlerClientCredentials(t *testing.T) {
+	client := models.OAuthClient{ClientID: "client-1", SecretHash: auth.HashToken("s3cret"), Scopes: "books:read books:write"}
+	r, ctrl := newOAuthTestRouter(t, client)
+	defer ctrl.Finish()
+
+	w := postForm(r, "/oauth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"books:read"}}, "client-1", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		Scope       string `json:"scope"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, "books:read", response.Scope)
+
+	// The issued token is active when introspected
+	w = postForm(r, "/oauth/introspect", url.Values{"token": {response.AccessToken}}, "client-1", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":true`)
+	assert.Contains(t, w.Body.String(), `"scope":"books:read"`)
+
+	w = postForm(r, "/oauth/introspect", url.Values{"token": {"garbage"}}, "client-1", "s3cret")
+	assert.Equal(t, `{"active":false}`, w.Body.String())
+}
+
+func TestTokenHandlerRejectsInvalidRequests(t *testing.T) {
+	client := models.OAuthClient{ClientID: "client-1", SecretHash: auth.HashToken("s3cret"), Scopes: "books:read"}
+	r, ctrl := newOAuthTestRouter(t, client)
+	defer ctrl.Finish()
+
+	w := postForm(r, "/oauth/token", url.Values{"grant_type": {"client_credentials"}}, "client-1", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_client")
+
+	w = postForm(r, "/oauth/token", url.Values{"grant_type": {"password"}}, "client-1", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unsupported_grant_type")
+
+	w = postForm(r, "/oauth/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"books:write"}}, "client-1", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_scope")
+}
+
+func TestTokenHandlerRejectsRevokedClient(t *testing.T) {
+	revokedAt := time.Now()
+	client := models.OAuthClient{ClientID: "client-1", SecretHash: auth.HashToken("s3cret"), Scopes: "books:read", RevokedAt: &revokedAt}
+	r, ctrl := newOAuthTestRouter(t, client)
+	defer ctrl.Finish()
+
+	w := postForm(r, "/oauth/token", url.Values{"grant_type": {"client_credentials"}}, "client-1", "s3cret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_client")
+}
+
+func TestCreateClientHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOAuthRepository(mockDB, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/oauth/clients", func(c *gin.Context) {
+		c.Set("username", "admin")
+		repo.CreateClientHandler(c)
+	})
+
+	expectCurrentUser(mockDB, models.User{ID: 3, Username: "admin", Role: models.RoleAdmin})
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+	var stored models.OAuthClient
+	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(client *models.OAuthClient) *gorm.DB {
+		stored = *client
+		return &gorm.DB{Error: nil}
+	})
+
+	body, _ := json.Marshal(models.CreateOAuthClient{Name: "indexer", Scopes: []string{auth.ScopeBooksRead}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/oauth/clients", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, uint(3), stored.UserID)
+	assert.Equal(t, "books:read", stored.Scopes)
+}
+
+func TestRevokeClientHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOAuthRepository(mockDB, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/oauth/clients/:id", repo.RevokeClientHandler)
+
+	mockDB.EXPECT().Where("client_id = ?", "client-1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.OAuthClient) = models.OAuthClient{ID: 4, ClientID: "client-1"}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+	db := dryRunDB(t)
+	var statement string
+	db.Callback().Update().After("gorm:update").Register("test:statement", func(tx *gorm.DB) {
+		statement = tx.Statement.SQL.String()
+	})
+	mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(func(value interface{}) *gorm.DB {
+		return db.Model(value)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/oauth/clients/client-1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, `UPDATE "o_auth_clients" SET "revoked_at"=$1,"updated_at"=$2 WHERE "id" = $3`, statement)
+
+	mockDB.EXPECT().Where("client_id = ?", "unknown").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).Return(mockDB)
+	mockDB.EXPECT().Error().Return(gorm.ErrRecordNotFound)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/oauth/clients/unknown", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
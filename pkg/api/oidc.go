@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL bounds how long a user has to complete the login at the provider
+const oidcStateTTL = 10 * time.Minute
+
+var errNoLinkedAccount = errors.New("no account linked to this identity")
+
+type OIDCRepository interface {
+	OIDCLoginHandler(c *gin.Context)
+	OIDCCallbackHandler(c *gin.Context)
+}
+
+// oidcRepository holds the configured identity providers and the shared resources
+type oidcRepository struct {
+	DB          database.Database
+	RedisClient cache.Cache
+	Ctx         *context.Context
+	Providers   map[string]*auth.OIDCProvider
+}
+
+// oidcLoginState is stored in Redis between the redirect and the callback
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+func NewOIDCRepository(db database.Database, redisClient cache.Cache, ctx *context.Context, providers map[string]*auth.OIDCProvider) *oidcRepository {
+	return &oidcRepository{
+		DB:          db,
+		RedisClient: redisClient,
+		Ctx:         ctx,
+		Providers:   providers,
+	}
+}
+
+// OIDCLoginHandler godoc
+// @Summary Start an OpenID Connect login
+// @Description Redirects the user to the identity provider using the authorization code flow with PKCE
+// @Tags user
+// @Param provider path string true "Identity provider name"
+// @Success 302 {string} string "Redirect to the identity provider"
+// @Failure 404 {string} string "Unknown identity provider"
+// @Failure 502 {string} string "Identity provider unavailable"
+// @Router /auth/oidc/{provider}/login [get]
+func (r *oidcRepository) OIDCLoginHandler(c *gin.Context) {
+	provider, ok := r.Providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	state := auth.GenerateOIDCState()
+	loginState := oidcLoginState{
+		Provider:     provider.Name,
+		Nonce:        auth.GenerateOIDCState(),
+		CodeVerifier: auth.GeneratePKCEVerifier(),
+	}
+
+	authURL, err := provider.AuthCodeURL(*r.Ctx, state, loginState.Nonce, auth.PKCEChallenge(loginState.CodeVerifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	serializedState, err := json.Marshal(loginState)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal login state"})
+		return
+	}
+	if err := r.RedisClient.Set(*r.Ctx, "oidc_state_"+state, serializedState, oidcStateTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store login state"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallbackHandler godoc
+// @Summary Complete an OpenID Connect login
+// @Description Exchanges the authorization code, verifies the ID token, links or provisions the user and returns a JWT token
+// @Tags user
+// @Produce json
+// @Param provider path string true "Identity provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State returned by the provider"
+// @Success 200 {string} string "JWT Token"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "No account linked to this identity"
+// @Failure 404 {string} string "Unknown identity provider"
+// @Router /auth/oidc/{provider}/callback [get]
+func (r *oidcRepository) OIDCCallbackHandler(c *gin.Context) {
+	provider, ok := r.Providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider returned an error: " + providerError})
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code or state"})
+		return
+	}
+
+	// The state is single use, whatever the outcome of the login
+	stateKey := "oidc_state_" + state
+	cachedState, err := r.RedisClient.Get(*r.Ctx, stateKey).Result()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+	r.RedisClient.Del(*r.Ctx, stateKey)
+
+	var loginState oidcLoginState
+	if err := json.Unmarshal([]byte(cachedState), &loginState); err != nil || loginState.Provider != provider.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+
+	tokenResponse, err := provider.Exchange(*r.Ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to exchange authorization code"})
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(*r.Ctx, tokenResponse.IDToken, loginState.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+
+	user, err := r.findOrProvisionUser(provider, claims)
+	if err != nil {
+		if errors.Is(err, errNoLinkedAccount) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No account linked to this identity"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+		return
+	}
+
+	token, err := auth.GenerateToken(user.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// findOrProvisionUser resolves the local user for an external identity. Known
+// identities map to their user. A verified email only links the account of
+// that name if the provider is trusted with emails and the account has no
+// local password, since anyone may have registered it. Otherwise a
+// provider:subject user is created when the provider allows it.
+func (r *oidcRepository) findOrProvisionUser(provider *auth.OIDCProvider, claims *auth.IDTokenClaims) (*models.User, error) {
+	var identity models.UserIdentity
+	var user models.User
+
+	err := r.DB.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity).Error()
+	if err == nil {
+		if err := r.DB.Where("id = ?", identity.UserID).First(&user).Error(); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	linked := false
+	if provider.TrustEmail && claims.Email != "" && claims.EmailVerified {
+		err = r.DB.Where("username = ?", claims.Email).First(&user).Error()
+		if err == nil {
+			if user.Password != "" {
+				return nil, errNoLinkedAccount
+			}
+			linked = true
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if !linked {
+		if !provider.AutoProvision {
+			return nil, errNoLinkedAccount
+		}
+		// Provisioned users have no local password and can only log in through the provider
+		user = models.User{Username: provider.Name + ":" + claims.Subject, Role: models.RoleUser}
+		err := r.DB.Create(&user).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errNoLinkedAccount
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity = models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := r.DB.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,59 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/oidc.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOIDCRepository is a mock of OIDCRepository interface.
+type MockOIDCRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCRepositoryMockRecorder
+}
+
+// MockOIDCRepositoryMockRecorder is the mock recorder for MockOIDCRepository.
+type MockOIDCRepositoryMockRecorder struct {
+	mock *MockOIDCRepository
+}
+
+// NewMockOIDCRepository creates a new mock instance.
+func NewMockOIDCRepository(ctrl *gomock.Controller) *MockOIDCRepository {
+	mock := &MockOIDCRepository{ctrl: ctrl}
+	mock.recorder = &MockOIDCRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCRepository) EXPECT() *MockOIDCRepositoryMockRecorder {
+	return m.recorder
+}
+
+// OIDCCallbackHandler mocks base method.
+func (m *MockOIDCRepository) OIDCCallbackHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OIDCCallbackHandler", c)
+}
+
+// OIDCCallbackHandler indicates an expected call of OIDCCallbackHandler.
+func (mr *MockOIDCRepositoryMockRecorder) OIDCCallbackHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OIDCCallbackHandler", reflect.TypeOf((*MockOIDCRepository)(nil).OIDCCallbackHandler), c)
+}
+
+// OIDCLoginHandler mocks base method.
+func (m *MockOIDCRepository) OIDCLoginHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OIDCLoginHandler", c)
+}
+
+// OIDCLoginHandler indicates an expected call of OIDCLoginHandler.
+func (mr *MockOIDCRepositoryMockRecorder) OIDCLoginHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OIDCLoginHandler", reflect.TypeOf((*MockOIDCRepository)(nil).OIDCLoginHandler), c)
+}
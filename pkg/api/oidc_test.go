@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestOIDCLoginHandlerUnknownProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewOIDCRepository(database.NewMockDatabase(ctrl), cache.NewMockCache(ctrl), &ctx, map[string]*auth.OIDCProvider{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/auth/oidc/:provider/login", repo.OIDCLoginHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/unknown/login", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOIDCCallbackHandlerRejectsUnknownState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	providers := map[string]*auth.OIDCProvider{"corp": {Name: "corp"}}
+	repo := NewOIDCRepository(database.NewMockDatabase(ctrl), mockCache, &ctx, providers)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/auth/oidc/:provider/callback", repo.OIDCCallbackHandler)
+
+	mockCache.EXPECT().Get(ctx, "oidc_state_forged").Return(redis.NewStringResult("", redis.Nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/corp/callback?code=abc&state=forged", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired state")
+}
+
+// expectNewIdentity makes the identity lookup of corp:sub find nothing
+func expectNewIdentity(mockDB *database.MockDatabase) {
+	mockDB.EXPECT().Where("provider = ? AND subject = ?", "corp", "sub").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).Return(mockDB)
+	mockDB.EXPECT().Error().Return(gorm.ErrRecordNotFound)
+}
+
+// expectUserNamed makes the lookup of the user named username load user
+func expectUserNamed(mockDB *database.MockDatabase, username string, user models.User) {
+	mockDB.EXPECT().Where("username = ?", username).Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.User) = user
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+}
+
+func TestFindOrProvisionUserIgnoresUntrustedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOIDCRepository(mockDB, nil, &ctx, nil)
+	provider := &auth.OIDCProvider{Name: "corp", AutoProvision: true}
+
+	// The local account named like the email is not looked at
+	expectNewIdentity(mockDB)
+	var created []interface{}
+	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(value interface{}) *gorm.DB {
+		created = append(created, value)
+		return &gorm.DB{}
+	}).Times(2)
+
+	user, err := repo.findOrProvisionUser(provider, &auth.IDTokenClaims{Subject: "sub", Email: "victim@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "corp:sub", user.Username)
+	assert.Equal(t, "corp", created[1].(*models.UserIdentity).Provider)
+}
+
+func TestFindOrProvisionUserRefusesPasswordAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOIDCRepository(mockDB, nil, &ctx, nil)
+	provider := &auth.OIDCProvider{Name: "corp", AutoProvision: true, TrustEmail: true}
+
+	expectNewIdentity(mockDB)
+	expectUserNamed(mockDB, "victim@example.com", models.User{ID: 7, Username: "victim@example.com", Password: "$2a$14$hash"})
+
+	_, err := repo.findOrProvisionUser(provider, &auth.IDTokenClaims{Subject: "sub", Email: "victim@example.com", EmailVerified: true})
+	assert.Equal(t, errNoLinkedAccount, err)
+}
+
+func TestFindOrProvisionUserLinksTrustedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewOIDCRepository(mockDB, nil, &ctx, nil)
+	provider := &auth.OIDCProvider{Name: "corp", TrustEmail: true}
+
+	expectNewIdentity(mockDB)
+	expectUserNamed(mockDB, "jane@example.com", models.User{ID: 7, Username: "jane@example.com"})
+	var identity *models.UserIdentity
+	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(value interface{}) *gorm.DB {
+		identity = value.(*models.UserIdentity)
+		return &gorm.DB{}
+	})
+
+	user, err := repo.findOrProvisionUser(provider, &auth.IDTokenClaims{Subject: "sub", Email: "jane@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), user.ID)
+	assert.Equal(t, uint(7), identity.UserID)
+}
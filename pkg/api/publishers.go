@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PublisherRepository interface {
+	FindPublishers(c *gin.Context)
+	CreatePublisher(c *gin.Context)
+	FindPublisher(c *gin.Context)
+	UpdatePublisher(c *gin.Context)
+	DeletePublisher(c *gin.Context)
+	PublisherBooks(c *gin.Context)
+}
+
+// publisherRepository manages the publishers books are linked to by name
+type publisherRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+}
+
+func NewPublisherRepository(db database.Database, ctx *context.Context) *publisherRepository {
+	return &publisherRepository{
+		DB:  db,
+		Ctx: ctx,
+	}
+}
+
+// linkPublisher sets the publisher ID of book to the publisher its publisher
+// name stands for, creating the publisher if there is none yet
+func linkPublisher(tx *gorm.DB, book *models.Book) error {
+	publisher := models.Publisher{Name: strings.TrimSpace(book.Publisher)}
+	if models.NameKey(publisher.Name) == "" {
+		book.PublisherID = nil
+		return nil
+	}
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name_key"}}, DoNothing: true}).Create(&publisher).Error
+	if err != nil {
+		return err
+	}
+	if publisher.ID == 0 {
+		if err := tx.Where("name_key = ?", publisher.NameKey).First(&publisher).Error; err != nil {
+			return err
+		}
+	}
+	book.PublisherID = &publisher.ID
+	return nil
+}
+
+// FindPublishers godoc
+// @Summary List publishers
+// @Description List publishers by name, optionally only those whose name contains a text
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name query string false "Text the name contains, case insensitive"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Publisher "Publishers"
+// @Failure 400 {string} string "Bad Request"
+// @Router /publishers [get]
+func (r *publisherRepository) FindPublishers(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := r.DB.Order("name, id")
+	if name := strings.ToLower(strings.TrimSpace(c.Query("name"))); name != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, "%"+escapeLike(name)+"%")
+	}
+	publishers := []models.Publisher{}
+	if err := db.Offset(offset).Limit(limit).Find(&publishers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publishers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": publishers})
+}
+
+// CreatePublisher godoc
+// @Summary Create a publisher
+// @Description Create a publisher. Names that only differ in case, spacing or punctuation from an existing publisher are rejected
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param input body models.CreatePublisher true "Publisher"
+// @Success 201 {object} models.Publisher "Created publisher"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {object} models.Publisher "Existing publisher with the same name"
+// @Router /publishers [post]
+func (r *publisherRepository) CreatePublisher(c *gin.Context) {
+	var input models.CreatePublisher
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !r.checkPublisherName(c, input.Name, 0) {
+		return
+	}
+
+	publisher := models.Publisher{Name: strings.TrimSpace(input.Name), Website: input.Website}
+	if err := r.DB.Create(&publisher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create publisher"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": publisher})
+}
+
+// checkPublisherName responds with a conflict if another publisher than id has the same name key
+func (r *publisherRepository) checkPublisherName(c *gin.Context, name string, id uint) bool {
+	key := models.NameKey(name)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must contain letters or digits"})
+		return false
+	}
+
+	var existing models.Publisher
+	err := r.DB.Where("name_key = ? AND id <> ?", key, id).First(&existing).Error()
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A publisher with this name already exists", "data": existing})
+		return false
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publishers"})
+		return false
+	}
+	return true
+}
+
+// FindPublisher godoc
+// @Summary Find a publisher by ID
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Success 200 {object} models.Publisher "Publisher"
+// @Failure 404 {string} string "publisher not found"
+// @Router /publishers/{id} [get]
+func (r *publisherRepository) FindPublisher(c *gin.Context) {
+	var publisher models.Publisher
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&publisher).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "publisher not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": publisher})
+}
+
+// UpdatePublisher godoc
+// @Summary Update a publisher by ID
+// @Description Replace the name and website of a publisher. Books keep their publisher name as printed and stay linked
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param input body models.CreatePublisher true "Publisher"
+// @Success 200 {object} models.Publisher "Updated publisher"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "publisher not found"
+// @Failure 409 {object} models.Publisher "Existing publisher with the same name"
+// @Router /publishers/{id} [put]
+func (r *publisherRepository) UpdatePublisher(c *gin.Context) {
+	var publisher models.Publisher
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&publisher).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "publisher not found"})
+		return
+	}
+
+	var input models.CreatePublisher
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !r.checkPublisherName(c, input.Name, publisher.ID) {
+		return
+	}
+
+	updated := publisher
+	updated.Name = strings.TrimSpace(input.Name)
+	updated.NameKey = models.NameKey(input.Name)
+	updated.Website = input.Website
+	if err := r.DB.Model(&publisher).Select("name", "name_key", "website").Updates(&updated).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publisher"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": publisher})
+}
+
+// DeletePublisher godoc
+// @Summary Delete a publisher by ID
+// @Description Delete a publisher no book is linked to, including books in the trash
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Param id path string true "Publisher ID"
+// @Success 204 {string} string "Successfully deleted publisher"
+// @Failure 404 {string} string "publisher not found"
+// @Failure 409 {string} string "The publisher has books"
+// @Router /publishers/{id} [delete]
+func (r *publisherRepository) DeletePublisher(c *gin.Context) {
+	var publisher models.Publisher
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&publisher).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "publisher not found"})
+		return
+	}
+
+	var books int64
+	if err := r.DB.Model(&models.Book{}).Unscoped().Where("publisher_id = ?", publisher.ID).Count(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete publisher"})
+		return
+	}
+	if books > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The publisher has books"})
+		return
+	}
+
+	if err := r.DB.Delete(&publisher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete publisher"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"data": true})
+}
+
+// PublisherBooks godoc
+// @Summary List the catalog of a publisher
+// @Description List the books of a publisher by title. GET /books takes a publisher_id filter to combine it with other filters
+// @Tags publishers
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Book "Books"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "publisher not found"
+// @Router /publishers/{id}/books [get]
+func (r *publisherRepository) PublisherBooks(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var publisher models.Publisher
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&publisher).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "publisher not found"})
+		return
+	}
+
+	books := []models.Book{}
+	err = r.DB.Model(&models.Book{}).
+		Where("publisher_id = ?", publisher.ID).
+		Order("title, id").
+		Offset(offset).Limit(limit).
+		Find(&books).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": books})
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/publishers.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPublisherRepository is a mock of PublisherRepository interface.
+type MockPublisherRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPublisherRepositoryMockRecorder
+}
+
+// MockPublisherRepositoryMockRecorder is the mock recorder for MockPublisherRepository.
+type MockPublisherRepositoryMockRecorder struct {
+	mock *MockPublisherRepository
+}
+
+// NewMockPublisherRepository creates a new mock instance.
+func NewMockPublisherRepository(ctrl *gomock.Controller) *MockPublisherRepository {
+	mock := &MockPublisherRepository{ctrl: ctrl}
+	mock.recorder = &MockPublisherRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPublisherRepository) EXPECT() *MockPublisherRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePublisher mocks base method.
+func (m *MockPublisherRepository) CreatePublisher(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreatePublisher", c)
+}
+
+// CreatePublisher indicates an expected call of CreatePublisher.
+func (mr *MockPublisherRepositoryMockRecorder) CreatePublisher(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePublisher", reflect.TypeOf((*MockPublisherRepository)(nil).CreatePublisher), c)
+}
+
+// DeletePublisher mocks base method.
+func (m *MockPublisherRepository) DeletePublisher(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeletePublisher", c)
+}
+
+// DeletePublisher indicates an expected call of DeletePublisher.
+func (mr *MockPublisherRepositoryMockRecorder) DeletePublisher(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePublisher", reflect.TypeOf((*MockPublisherRepository)(nil).DeletePublisher), c)
+}
+
+// FindPublisher mocks base method.
+func (m *MockPublisherRepository) FindPublisher(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindPublisher", c)
+}
+
+// FindPublisher indicates an expected call of FindPublisher.
+func (mr *MockPublisherRepositoryMockRecorder) FindPublisher(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPublisher", reflect.TypeOf((*MockPublisherRepository)(nil).FindPublisher), c)
+}
+
+// FindPublishers mocks base method.
+func (m *MockPublisherRepository) FindPublishers(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindPublishers", c)
+}
+
+// FindPublishers indicates an expected call of FindPublishers.
+func (mr *MockPublisherRepositoryMockRecorder) FindPublishers(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPublishers", reflect.TypeOf((*MockPublisherRepository)(nil).FindPublishers), c)
+}
+
+// PublisherBooks mocks base method.
+func (m *MockPublisherRepository) PublisherBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PublisherBooks", c)
+}
+
+// PublisherBooks indicates an expected call of PublisherBooks.
+func (mr *MockPublisherRepositoryMockRecorder) PublisherBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublisherBooks", reflect.TypeOf((*MockPublisherRepository)(nil).PublisherBooks), c)
+}
+
+// UpdatePublisher mocks base method.
+func (m *MockPublisherRepository) UpdatePublisher(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatePublisher", c)
+}
+
+// UpdatePublisher indicates an expected call of UpdatePublisher.
+func (mr *MockPublisherRepositoryMockRecorder) UpdatePublisher(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePublisher", reflect.TypeOf((*MockPublisherRepository)(nil).UpdatePublisher), c)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLinkPublisher(t *testing.T) {
+	var statements []string
+	db := dryRunDB(t)
+	db.Callback().Create().After("gorm:create").Register("test:statement", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	// The publisher exists already, so it is looked up by its key
+	db.Callback().Query().After("gorm:query").Register("test:row", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		tx.Statement.Dest.(*models.Publisher).ID = 3
+		tx.RowsAffected = 1
+	})
+
+	book := models.Book{Publisher: " Chilton Books "}
+	assert.NoError(t, linkPublisher(db, &book))
+	assert.Equal(t, uint(3), *book.PublisherID)
+	assert.Equal(t, []string{
+		`INSERT INTO "publishers" ("name","name_key","website","created_at","updated_at") VALUES ($1,$2,$3,$4,$5) ON CONFLICT ("name_key") DO NOTHING RETURNING "id"`,
+		`SELECT * FROM "publishers" WHERE name_key = $1 ORDER BY "publishers"."id" LIMIT $2`,
+	}, statements)
+
+	book.Publisher = ""
+	assert.NoError(t, linkPublisher(db, &book))
+	assert.Nil(t, book.PublisherID)
+}
+
+func TestDeletePublisherWithBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewPublisherRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "3").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Publisher) = models.Publisher{ID: 3, Name: "Chilton Books"}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	var sql string
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		*tx.Statement.Dest.(*int64) = 1
+		tx.RowsAffected = 1
+	})
+	mockDB.EXPECT().Model(&models.Book{}).Return(db.Model(&models.Book{}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/publishers/:id", repo.DeletePublisher)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/publishers/3", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	// Books in the trash count too
+	assert.Equal(t, `SELECT count(*) FROM "books" WHERE publisher_id = $1`, sql)
+}
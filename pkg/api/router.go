@@ -2,9 +2,11 @@ package api
 
 import (
 	"context"
+	"golang-rest-api-template/pkg/auth"
 	"golang-rest-api-template/pkg/cache"
 	"golang-rest-api-template/pkg/database"
 	"golang-rest-api-template/pkg/middleware"
+	"golang-rest-api-template/pkg/models"
 	"time"
 
 	docs "golang-rest-api-template/docs"
@@ -25,11 +27,20 @@ func ContextMiddleware(bookRepository BookRepository) gin.HandlerFunc {
 	}
 }
 
-func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db database.Database, redisClient cache.Cache, ctx *context.Context) *gin.Engine {
+func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db database.Database, redisClient cache.Cache, exportRepository ExportRepository, ctx *context.Context) *gin.Engine {
 	bookRepository := NewBookRepository(db, redisClient, ctx)
-	userRepository := NewUserRepository(db, ctx)
+	userRepository := NewUserRepository(db, redisClient, ctx)
+	oidcRepository := NewOIDCRepository(db, redisClient, ctx, auth.LoadOIDCProviders())
+	oauthRepository := NewOAuthRepository(db, ctx)
+	tokenRepository := NewTokenRepository(db, ctx)
+	authorRepository := NewAuthorRepository(db, ctx)
+	publisherRepository := NewPublisherRepository(db, ctx)
+	seriesRepository := NewSeriesRepository(db, ctx)
+
+	clientAuth := middleware.ClientAuth(redisClient)
 
 	r := gin.Default()
+	r.Use(middleware.RequestID())
 	r.Use(ContextMiddleware(bookRepository))
 
 	//r.Use(gin.Logger())
@@ -40,19 +51,89 @@ func NewRouter(logger *zap.Logger, mongoCollection *mongo.Collection, db databas
 	}
 	r.Use(middleware.Cors())
 	r.Use(middleware.RateLimiter(rate.Every(1*time.Minute), 60)) // 60 requests per minute
+	if auth.SessionsEnabled() {
+		r.Use(middleware.Session(redisClient))
+		r.Use(middleware.CSRF())
+	}
 
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	v1 := r.Group("/api/v1")
 	{
 		v1.GET("/", bookRepository.Healthcheck)
-		v1.GET("/books", middleware.APIKeyAuth(), bookRepository.FindBooks)
-		v1.POST("/books", middleware.APIKeyAuth(), middleware.JWTAuth(), bookRepository.CreateBook)
-		v1.GET("/books/:id", middleware.APIKeyAuth(), bookRepository.FindBook)
-		v1.PUT("/books/:id", middleware.APIKeyAuth(), bookRepository.UpdateBook)
-		v1.DELETE("/books/:id", middleware.APIKeyAuth(), bookRepository.DeleteBook)
-
-		v1.POST("/login", middleware.APIKeyAuth(), userRepository.LoginHandler)
-		v1.POST("/register", middleware.APIKeyAuth(), userRepository.RegisterHandler)
+		v1.GET("/books", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.FindBooks)
+		v1.POST("/books", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.CreateBook)
+		v1.POST("/books/bulk", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.BulkBooks)
+		v1.POST("/books/import", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.ImportBooks)
+		v1.GET("/books/import/:id/report", clientAuth, middleware.JWTAuth(db), bookRepository.ImportReport)
+		v1.GET("/books/export", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.ExportBooks)
+		v1.GET("/books/search", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.SearchBooks)
+		v1.GET("/books/suggest", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.SuggestBooks)
+		v1.GET("/books/trash", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), middleware.RequireRole(db, models.RoleAdmin), bookRepository.TrashedBooks)
+		v1.DELETE("/books/trash/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleAdmin), bookRepository.PurgeBook)
+		v1.GET("/books/duplicates", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), middleware.RequireRole(db, models.RoleAdmin), bookRepository.FindDuplicates)
+		v1.GET("/books/:id", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.FindBook)
+		v1.PUT("/books/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.UpdateBook)
+		v1.PATCH("/books/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.PatchBook)
+		v1.DELETE("/books/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.DeleteBook)
+		v1.GET("/books/:id/history", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.BookHistory)
+		v1.POST("/books/:id/revert/:revision", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.RevertBook)
+		v1.POST("/books/:id/restore", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleAdmin), bookRepository.RestoreBook)
+		v1.POST("/books/:id/merge", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleAdmin), bookRepository.MergeBooks)
+		v1.POST("/books/:id/cover", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.UploadCover)
+		v1.DELETE("/books/:id/cover", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.DeleteCover)
+		v1.GET("/books/:id/authors", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.BookAuthors)
+		v1.PUT("/books/:id/authors", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), bookRepository.SetBookAuthors)
+
+		v1.GET("/covers/*key", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.FindCover)
+
+		v1.GET("/authors", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), authorRepository.FindAuthors)
+		v1.POST("/authors", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), authorRepository.CreateAuthor)
+		v1.GET("/authors/:id", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), authorRepository.FindAuthor)
+		v1.PUT("/authors/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), authorRepository.UpdateAuthor)
+		v1.DELETE("/authors/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), authorRepository.DeleteAuthor)
+		v1.GET("/authors/:id/books", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), authorRepository.AuthorBooks)
+
+		v1.GET("/works/:id", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.FindWork)
+		v1.GET("/works/:id/editions", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), bookRepository.WorkEditions)
+		v1.PUT("/works/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleLibrarian, models.RoleAdmin), bookRepository.UpdateWork)
+		v1.POST("/works/:id/merge", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleLibrarian, models.RoleAdmin), bookRepository.MergeWorks)
+		v1.POST("/works/:id/split", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), middleware.RequireRole(db, models.RoleLibrarian, models.RoleAdmin), bookRepository.SplitWork)
+
+		v1.GET("/publishers", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), publisherRepository.FindPublishers)
+		v1.POST("/publishers", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), publisherRepository.CreatePublisher)
+		v1.GET("/publishers/:id", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), publisherRepository.FindPublisher)
+		v1.PUT("/publishers/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), publisherRepository.UpdatePublisher)
+		v1.DELETE("/publishers/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), publisherRepository.DeletePublisher)
+		v1.GET("/publishers/:id/books", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), publisherRepository.PublisherBooks)
+
+		v1.GET("/series", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), seriesRepository.ListSeries)
+		v1.POST("/series", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), seriesRepository.CreateSeries)
+		v1.GET("/series/:id", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), seriesRepository.FindSeries)
+		v1.PUT("/series/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), seriesRepository.UpdateSeries)
+		v1.DELETE("/series/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksWrite), seriesRepository.DeleteSeries)
+		v1.GET("/series/:id/books", clientAuth, middleware.OptionalJWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), seriesRepository.SeriesBooks)
+
+		v1.POST("/exports", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), exportRepository.CreateExport)
+		v1.GET("/exports/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), exportRepository.FindExport)
+		v1.GET("/exports/:id/download", clientAuth, middleware.JWTAuth(db), middleware.RequireScope(auth.ScopeBooksRead), exportRepository.DownloadExport)
+
+		v1.POST("/login", clientAuth, userRepository.LoginHandler)
+		v1.POST("/logout", clientAuth, userRepository.LogoutHandler)
+		v1.POST("/register", clientAuth, userRepository.RegisterHandler)
+
+		// Browser redirects to and from the identity provider cannot carry the API key
+		v1.GET("/auth/oidc/:provider/login", oidcRepository.OIDCLoginHandler)
+		v1.GET("/auth/oidc/:provider/callback", oidcRepository.OIDCCallbackHandler)
+
+		v1.POST("/oauth/clients", clientAuth, middleware.JWTAuth(db), middleware.RequireRole(db, models.RoleAdmin), oauthRepository.CreateClientHandler)
+		v1.GET("/oauth/clients", clientAuth, middleware.JWTAuth(db), middleware.RequireRole(db, models.RoleAdmin), oauthRepository.ListClientsHandler)
+		v1.DELETE("/oauth/clients/:id", clientAuth, middleware.JWTAuth(db), middleware.RequireRole(db, models.RoleAdmin), oauthRepository.RevokeClientHandler)
+		v1.POST("/oauth/token", oauthRepository.TokenHandler)
+		v1.POST("/oauth/introspect", oauthRepository.IntrospectHandler)
+
+		v1.POST("/tokens", clientAuth, middleware.JWTAuth(db), tokenRepository.CreateTokenHandler)
+		v1.GET("/tokens", clientAuth, middleware.JWTAuth(db), tokenRepository.ListTokensHandler)
+		v1.DELETE("/tokens/:id", clientAuth, middleware.JWTAuth(db), tokenRepository.RevokeTokenHandler)
 	}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
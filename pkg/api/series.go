@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SeriesRepository interface {
+	ListSeries(c *gin.Context)
+	CreateSeries(c *gin.Context)
+	FindSeries(c *gin.Context)
+	UpdateSeries(c *gin.Context)
+	DeleteSeries(c *gin.Context)
+	SeriesBooks(c *gin.Context)
+}
+
+// seriesRepository manages the series books can be volumes of
+type seriesRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+}
+
+func NewSeriesRepository(db database.Database, ctx *context.Context) *seriesRepository {
+	return &seriesRepository{
+		DB:  db,
+		Ctx: ctx,
+	}
+}
+
+// checkSeries returns errUnknownSeries if id is set but names no series
+func checkSeries(tx *gorm.DB, id *uint) error {
+	if id == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Series{}).Where("id = ?", *id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errUnknownSeries
+	}
+	return nil
+}
+
+// sameID reports whether two optional IDs are both unset or equal
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ListSeries godoc
+// @Summary List series
+// @Description List series by name, optionally only those whose name contains a text
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name query string false "Text the name contains, case insensitive"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Series "Series"
+// @Failure 400 {string} string "Bad Request"
+// @Router /series [get]
+func (r *seriesRepository) ListSeries(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := r.DB.Order("name, id")
+	if name := strings.ToLower(strings.TrimSpace(c.Query("name"))); name != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, "%"+escapeLike(name)+"%")
+	}
+	series := []models.Series{}
+	if err := db.Offset(offset).Limit(limit).Find(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// CreateSeries godoc
+// @Summary Create a series
+// @Tags series
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param input body models.CreateSeries true "Series"
+// @Success 201 {object} models.Series "Created series"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /series [post]
+func (r *seriesRepository) CreateSeries(c *gin.Context) {
+	var input models.CreateSeries
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series := models.Series{Name: strings.TrimSpace(input.Name), Description: input.Description}
+	if err := r.DB.Create(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": series})
+}
+
+// FindSeries godoc
+// @Summary Find a series by ID
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} models.Series "Series"
+// @Failure 404 {string} string "series not found"
+// @Router /series/{id} [get]
+func (r *seriesRepository) FindSeries(c *gin.Context) {
+	var series models.Series
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&series).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// UpdateSeries godoc
+// @Summary Update a series by ID
+// @Tags series
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param input body models.CreateSeries true "Series"
+// @Success 200 {object} models.Series "Updated series"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "series not found"
+// @Router /series/{id} [put]
+func (r *seriesRepository) UpdateSeries(c *gin.Context) {
+	var series models.Series
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&series).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+
+	var input models.CreateSeries
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated := series
+	updated.Name = strings.TrimSpace(input.Name)
+	updated.Description = input.Description
+	if err := r.DB.Model(&series).Select("name", "description").Updates(&updated).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// DeleteSeries godoc
+// @Summary Delete a series by ID
+// @Description Delete a series without volumes, including books in the trash
+// @Tags series
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Param id path string true "Series ID"
+// @Success 204 {string} string "Successfully deleted series"
+// @Failure 404 {string} string "series not found"
+// @Failure 409 {string} string "The series has books"
+// @Router /series/{id} [delete]
+func (r *seriesRepository) DeleteSeries(c *gin.Context) {
+	var series models.Series
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&series).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+
+	var books int64
+	if err := r.DB.Model(&models.Book{}).Unscoped().Where("series_id = ?", series.ID).Count(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+	if books > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The series has books"})
+		return
+	}
+
+	if err := r.DB.Delete(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"data": true})
+}
+
+// SeriesBooks godoc
+// @Summary List a series in reading order
+// @Description List the books of a series by volume. Books without a volume come last, by publication date
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Success 200 {array} models.Book "Books"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "series not found"
+// @Router /series/{id}/books [get]
+func (r *seriesRepository) SeriesBooks(c *gin.Context) {
+	offset, err := parseOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var series models.Series
+	if err := r.DB.Where("id = ?", c.Param("id")).First(&series).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+
+	books := []models.Book{}
+	err = r.DB.Model(&models.Book{}).
+		Where("series_id = ?", series.ID).
+		Order("series_volume IS NULL, series_volume, published_at, id").
+		Offset(offset).Limit(limit).
+		Find(&books).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": books})
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/series.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSeriesRepository is a mock of SeriesRepository interface.
+type MockSeriesRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSeriesRepositoryMockRecorder
+}
+
+// MockSeriesRepositoryMockRecorder is the mock recorder for MockSeriesRepository.
+type MockSeriesRepositoryMockRecorder struct {
+	mock *MockSeriesRepository
+}
+
+// NewMockSeriesRepository creates a new mock instance.
+func NewMockSeriesRepository(ctrl *gomock.Controller) *MockSeriesRepository {
+	mock := &MockSeriesRepository{ctrl: ctrl}
+	mock.recorder = &MockSeriesRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSeriesRepository) EXPECT() *MockSeriesRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateSeries mocks base method.
+func (m *MockSeriesRepository) CreateSeries(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateSeries", c)
+}
+
+// CreateSeries indicates an expected call of CreateSeries.
+func (mr *MockSeriesRepositoryMockRecorder) CreateSeries(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSeries", reflect.TypeOf((*MockSeriesRepository)(nil).CreateSeries), c)
+}
+
+// DeleteSeries mocks base method.
+func (m *MockSeriesRepository) DeleteSeries(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteSeries", c)
+}
+
+// DeleteSeries indicates an expected call of DeleteSeries.
+func (mr *MockSeriesRepositoryMockRecorder) DeleteSeries(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSeries", reflect.TypeOf((*MockSeriesRepository)(nil).DeleteSeries), c)
+}
+
+// FindSeries mocks base method.
+func (m *MockSeriesRepository) FindSeries(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FindSeries", c)
+}
+
+// FindSeries indicates an expected call of FindSeries.
+func (mr *MockSeriesRepositoryMockRecorder) FindSeries(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSeries", reflect.TypeOf((*MockSeriesRepository)(nil).FindSeries), c)
+}
+
+// ListSeries mocks base method.
+func (m *MockSeriesRepository) ListSeries(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListSeries", c)
+}
+
+// ListSeries indicates an expected call of ListSeries.
+func (mr *MockSeriesRepositoryMockRecorder) ListSeries(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSeries", reflect.TypeOf((*MockSeriesRepository)(nil).ListSeries), c)
+}
+
+// SeriesBooks mocks base method.
+func (m *MockSeriesRepository) SeriesBooks(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SeriesBooks", c)
+}
+
+// SeriesBooks indicates an expected call of SeriesBooks.
+func (mr *MockSeriesRepositoryMockRecorder) SeriesBooks(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SeriesBooks", reflect.TypeOf((*MockSeriesRepository)(nil).SeriesBooks), c)
+}
+
+// UpdateSeries mocks base method.
+func (m *MockSeriesRepository) UpdateSeries(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateSeries", c)
+}
+
+// UpdateSeries indicates an expected call of UpdateSeries.
+func (mr *MockSeriesRepositoryMockRecorder) UpdateSeries(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSeries", reflect.TypeOf((*MockSeriesRepository)(nil).UpdateSeries), c)
+}
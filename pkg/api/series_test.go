@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSeriesBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewSeriesRepository(mockDB, &ctx)
+
+	mockDB.EXPECT().Where("id = ?", "3").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.Series) = models.Series{ID: 3, Name: "Dune Chronicles"}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	var sql string
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:statement", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	mockDB.EXPECT().Model(&models.Book{}).Return(db.Model(&models.Book{}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.GET("/series/:id/books", repo.SeriesBooks)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/series/3/books", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// Books without a volume come last
+	assert.Equal(t, `SELECT * FROM "books" WHERE series_id = $1 AND "books"."deleted_at" IS NULL ORDER BY series_volume IS NULL, series_volume, published_at, id LIMIT $2`, sql)
+}
+
+func TestCreateBookUnknownSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewBookRepository(mockDB, nil, &ctx)
+
+	var created int
+	db := dryRunDB(t)
+	db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		created++
+	})
+	expectTransaction(mockDB, db)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) { c.Set("appCtx", repo) })
+	r.POST("/books", repo.CreateBook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"title": "Dune", "author": "Frank Herbert", "series_id": 9, "series_volume": 1}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error": "Invalid book", "fields": {"series_id": "does not exist"}}`, w.Body.String())
+	assert.Equal(t, 0, created)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TokenRepository interface {
+	CreateTokenHandler(c *gin.Context)
+	ListTokensHandler(c *gin.Context)
+	RevokeTokenHandler(c *gin.Context)
+}
+
+// tokenRepository manages the personal access tokens of the authenticated user
+type tokenRepository struct {
+	DB  database.Database
+	Ctx *context.Context
+}
+
+func NewTokenRepository(db database.Database, ctx *context.Context) *tokenRepository {
+	return &tokenRepository{
+		DB:  db,
+		Ctx: ctx,
+	}
+}
+
+// CreateTokenHandler godoc
+// @Summary Create a personal access token
+// @Description Creates a named, scope limited token for the authenticated user. The token is only returned once.
+// @Tags tokens
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Accept  json
+// @Produce  json
+// @Param   input     body   models.CreatePersonalAccessToken   true   "Personal access token object"
+// @Success 201 {object} models.PersonalAccessToken "Successfully created token"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Router /tokens [post]
+func (r *tokenRepository) CreateTokenHandler(c *gin.Context) {
+	// Tokens can't be used to mint further tokens
+	if _, limited := c.Get("scopes"); limited {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	user, err := currentUser(c, r.DB)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var input models.CreatePersonalAccessToken
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+
+	plainToken := auth.GeneratePersonalAccessToken()
+	token := models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      input.Name,
+		TokenHash: auth.HashToken(plainToken),
+		Prefix:    plainToken[:len(auth.PersonalAccessTokenPrefix)+8],
+		Scopes:    strings.Join(input.Scopes, " "),
+		ExpiresAt: time.Now().AddDate(0, 0, input.ExpiresInDays),
+	}
+	if err := r.DB.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": token, "token": plainToken})
+}
+
+// ListTokensHandler godoc
+// @Summary List personal access tokens
+// @Description Lists the personal access tokens of the authenticated user
+// @Tags tokens
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Success 200 {array} models.PersonalAccessToken "Successfully retrieved tokens"
+// @Failure 401 {string} string "Unauthorized"
+// @Router /tokens [get]
+func (r *tokenRepository) ListTokensHandler(c *gin.Context) {
+	var tokens []models.PersonalAccessToken
+
+	user, err := currentUser(c, r.DB)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	r.DB.Where("user_id = ?", user.ID).Find(&tokens)
+
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// RevokeTokenHandler godoc
+// @Summary Revoke a personal access token
+// @Description Revokes a personal access token of the authenticated user
+// @Tags tokens
+// @Security ApiKeyAuth
+// @Security JwtAuth
+// @Produce  json
+// @Param id path string true "Token ID"
+// @Success 204 {string} string "Successfully revoked token"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "token not found"
+// @Router /tokens/{id} [delete]
+func (r *tokenRepository) RevokeTokenHandler(c *gin.Context) {
+	var token models.PersonalAccessToken
+
+	user, err := currentUser(c, r.DB)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := r.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&token).Error(); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+		return
+	}
+
+	if token.RevokedAt == nil {
+		r.DB.Model(&token).Update("revoked_at", time.Now())
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"data": true})
+}
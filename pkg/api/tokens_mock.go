@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/api/tokens.go
+
+// Package api is a generated GoMock package.
+package api
+
+import (
+	reflect "reflect"
+
+	gin "github.com/gin-gonic/gin"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTokenRepository is a mock of TokenRepository interface.
+type MockTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRepositoryMockRecorder
+}
+
+// MockTokenRepositoryMockRecorder is the mock recorder for MockTokenRepository.
+type MockTokenRepositoryMockRecorder struct {
+	mock *MockTokenRepository
+}
+
+// NewMockTokenRepository creates a new mock instance.
+func NewMockTokenRepository(ctrl *gomock.Controller) *MockTokenRepository {
+	mock := &MockTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRepository) EXPECT() *MockTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateTokenHandler mocks base method.
+func (m *MockTokenRepository) CreateTokenHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateTokenHandler", c)
+}
+
+// CreateTokenHandler indicates an expected call of CreateTokenHandler.
+func (mr *MockTokenRepositoryMockRecorder) CreateTokenHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTokenHandler", reflect.TypeOf((*MockTokenRepository)(nil).CreateTokenHandler), c)
+}
+
+// ListTokensHandler mocks base method.
+func (m *MockTokenRepository) ListTokensHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListTokensHandler", c)
+}
+
+// ListTokensHandler indicates an expected call of ListTokensHandler.
+func (mr *MockTokenRepositoryMockRecorder) ListTokensHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTokensHandler", reflect.TypeOf((*MockTokenRepository)(nil).ListTokensHandler), c)
+}
+
+// RevokeTokenHandler mocks base method.
+func (m *MockTokenRepository) RevokeTokenHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RevokeTokenHandler", c)
+}
+
+// RevokeTokenHandler indicates an expected call of RevokeTokenHandler.
+func (mr *MockTokenRepositoryMockRecorder) RevokeTokenHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokenHandler", reflect.TypeOf((*MockTokenRepository)(nil).RevokeTokenHandler), c)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func expectCurrentUser(mockDB *database.MockDatabase, user models.User) {
+	mockDB.EXPECT().Where("username = ?", user.Username).Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.User) = user
+		return mockDB
+	})
+}
+
+func TestCreateTokenHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewTokenRepository(mockDB, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/tokens", func(c *gin.Context) {
+		c.Set("username", "jane")
+		repo.CreateTokenHandler(c)
+	})
+
+	expectCurrentUser(mockDB, models.User{ID: 7, Username: "jane"})
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+
+	var stored models.PersonalAccessToken
+	mockDB.EXPECT().Create(gomock.Any()).DoAndReturn(func(token *models.PersonalAccessToken) *gorm.DB {
+		stored = *token
+		return &gorm.DB{Error: nil}
+	})
+
+	body, _ := json.Marshal(models.CreatePersonalAccessToken{Name: "ci", Scopes: []string{auth.ScopeBooksRead}, ExpiresInDays: 30})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, strings.HasPrefix(response.Token, auth.PersonalAccessTokenPrefix))
+	assert.Equal(t, auth.HashToken(response.Token), stored.TokenHash, "only the hash of the token must be stored")
+	assert.Equal(t, uint(7), stored.UserID)
+	assert.Equal(t, "books:read", stored.Scopes)
+	assert.NotContains(t, w.Body.String(), stored.TokenHash)
+}
+
+func TestCreateTokenHandlerRejectsScopedCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewTokenRepository(database.NewMockDatabase(ctrl), &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/tokens", func(c *gin.Context) {
+		c.Set("username", "jane")
+		c.Set("scopes", "books:read")
+		repo.CreateTokenHandler(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/tokens", strings.NewReader(`{}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRevokeTokenHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewTokenRepository(mockDB, &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.DELETE("/tokens/:id", func(c *gin.Context) {
+		c.Set("username", "jane")
+		repo.RevokeTokenHandler(c)
+	})
+
+	expectCurrentUser(mockDB, models.User{ID: 7, Username: "jane"})
+	mockDB.EXPECT().Where("id = ? AND user_id = ?", "3", uint(7)).Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.PersonalAccessToken) = models.PersonalAccessToken{ID: 3, UserID: 7}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil).AnyTimes()
+	mockDB.EXPECT().Model(gomock.Any()).Return(dryRunDB(t)).Times(1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/tokens/3", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
 	"golang-rest-api-template/pkg/database"
 	"golang-rest-api-template/pkg/models"
 	"net/http"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -17,19 +19,24 @@ import (
 
 type UserRepository interface {
 	LoginHandler(c *gin.Context)
+	LogoutHandler(c *gin.Context)
 	RegisterHandler(c *gin.Context)
 }
 
 // bookRepository holds shared resources like database and Redis client
 type userRepository struct {
-	DB  database.Database
-	Ctx *context.Context
+	DB             database.Database
+	RedisClient    cache.Cache
+	Ctx            *context.Context
+	SessionCookies bool
 }
 
-func NewUserRepository(db database.Database, ctx *context.Context) *userRepository {
+func NewUserRepository(db database.Database, redisClient cache.Cache, ctx *context.Context) *userRepository {
 	return &userRepository{
-		DB:  db,
-		Ctx: ctx,
+		DB:             db,
+		RedisClient:    redisClient,
+		Ctx:            ctx,
+		SessionCookies: auth.SessionsEnabled(),
 	}
 }
 
@@ -38,7 +45,7 @@ func NewUserRepository(db database.Database, ctx *context.Context) *userReposito
 // LoginHandler godoc
 // @Summary Authenticate a user
 // @Schemes
-// @Description Authenticates a user using username and password, returns a JWT token if successful
+// @Description Authenticates a user using username and password, returns a JWT token if successful. When session cookies are enabled it also starts a session and sets the session and CSRF cookies.
 // @Tags user
 // @Security ApiKeyAuth
 // @Accept  json
@@ -82,7 +89,58 @@ func (r *userRepository) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	if !r.SessionCookies {
+		c.JSON(http.StatusOK, gin.H{"token": token})
+		return
+	}
+
+	session, err := auth.CreateSession(*r.Ctx, r.RedisClient, dbUser.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating session"})
+		return
+	}
+	setSessionCookies(c, session.ID, session.CSRFToken, int(auth.SessionTTL.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{"token": token, "csrf_token": session.CSRFToken})
+}
+
+// LogoutHandler godoc
+// @Summary End the browser session
+// @Description Deletes the session started by login and clears the session and CSRF cookies
+// @Tags user
+// @Security ApiKeyAuth
+// @Produce  json
+// @Success 204 {string} string "Successfully logged out"
+// @Router /logout [post]
+func (r *userRepository) LogoutHandler(c *gin.Context) {
+	if sessionID, err := c.Cookie(auth.SessionCookieName); err == nil && sessionID != "" {
+		auth.DeleteSession(*r.Ctx, r.RedisClient, sessionID)
+	}
+	setSessionCookies(c, "", "", -1)
+
+	c.JSON(http.StatusNoContent, gin.H{"data": true})
+}
+
+// setSessionCookies sets the HttpOnly session cookie and the CSRF cookie,
+// which scripts must be able to read to echo it in the X-CSRF-Token header.
+func setSessionCookies(c *gin.Context, sessionID, csrfToken string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     auth.SessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     auth.CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
 }
 
 // RegisterHandler godoc
@@ -105,6 +163,11 @@ func (r *userRepository) RegisterHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Names with a colon are reserved for users provisioned by OpenID Connect providers
+	if strings.Contains(user.Username, ":") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username must not contain ':'"})
+		return
+	}
 
 	// Hash the password
 	hashedPassword, err := auth.HashPassword(user.Password)
@@ -114,7 +177,7 @@ func (r *userRepository) RegisterHandler(c *gin.Context) {
 	}
 
 	// Create new user
-	newUser := models.User{Username: user.Username, Password: hashedPassword}
+	newUser := models.User{Username: user.Username, Password: hashedPassword, Role: models.RoleUser}
 
 	// Save the user to the database
 	if err := r.DB.Create(&newUser).Error; err != nil {
@@ -124,3 +187,17 @@ func (r *userRepository) RegisterHandler(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Registration successful"})
 }
+
+// currentUser loads the user authenticated by JWTAuth
+func currentUser(c *gin.Context, db database.Database) (*models.User, error) {
+	var user models.User
+
+	username := c.GetString("username")
+	if username == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := db.Where("username = ?", username).First(&user).Error(); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
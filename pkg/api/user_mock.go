@@ -46,6 +46,18 @@ func (mr *MockUserRepositoryMockRecorder) LoginHandler(c interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginHandler", reflect.TypeOf((*MockUserRepository)(nil).LoginHandler), c)
 }
 
+// LogoutHandler mocks base method.
+func (m *MockUserRepository) LogoutHandler(c *gin.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LogoutHandler", c)
+}
+
+// LogoutHandler indicates an expected call of LogoutHandler.
+func (mr *MockUserRepositoryMockRecorder) LogoutHandler(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutHandler", reflect.TypeOf((*MockUserRepository)(nil).LogoutHandler), c)
+}
+
 // RegisterHandler mocks base method.
 func (m *MockUserRepository) RegisterHandler(c *gin.Context) {
 	m.ctrl.T.Helper()
@@ -1 +1,115 @@
 package api
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginHandlerSetsSessionCookies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+	repo := NewUserRepository(mockDB, mockCache, &ctx)
+	repo.SessionCookies = true
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/login", repo.LoginHandler)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	mockDB.EXPECT().Where("username = ?", "jane").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.User) = models.User{ID: 1, Username: "jane", Password: string(hashedPassword)}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+	mockCache.EXPECT().Set(ctx, gomock.Any(), gomock.Any(), auth.SessionTTL).Return(redis.NewStatusResult("OK", nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"jane","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	for _, cookie := range cookies {
+		assert.True(t, cookie.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+		switch cookie.Name {
+		case auth.SessionCookieName:
+			assert.True(t, cookie.HttpOnly, "session cookie must not be readable by scripts")
+		case auth.CSRFCookieName:
+			assert.False(t, cookie.HttpOnly, "csrf cookie must be readable by scripts")
+			assert.Contains(t, w.Body.String(), cookie.Value)
+		default:
+			t.Errorf("unexpected cookie %s", cookie.Name)
+		}
+	}
+}
+
+func TestLoginHandlerWithoutSessionCookies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	repo := NewUserRepository(mockDB, cache.NewMockCache(ctrl), &ctx)
+	repo.SessionCookies = false
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/login", repo.LoginHandler)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	mockDB.EXPECT().Where("username = ?", "jane").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+		*dest.(*models.User) = models.User{ID: 1, Username: "jane", Password: string(hashedPassword)}
+		return mockDB
+	})
+	mockDB.EXPECT().Error().Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"jane","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+	assert.Contains(t, w.Body.String(), "token")
+}
+
+func TestRegisterHandlerReservesProviderNames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	repo := NewUserRepository(database.NewMockDatabase(ctrl), cache.NewMockCache(ctrl), &ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/register", repo.RegisterHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"corp:sub","password":"secret"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,8 +1,9 @@
 package auth
 
 import (
-	"crypto/rand"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 // Claims struct to be encoded to JWT
 type Claims struct {
 	Username string `json:"username"`
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -28,10 +31,13 @@ func GenerateToken(username string) (string, error) {
 	expirationTime := time.Now().Add(5 * time.Minute).Unix()
 
 	// Create the JWT claims, which includes the username and expiration time
-	claims := &jwt.StandardClaims{
-		// In JWT, the expiry time is expressed as unix milliseconds
-		ExpiresAt: expirationTime,
-		Issuer:    username,
+	claims := &Claims{
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			// In JWT, the expiry time is expressed as unix milliseconds
+			ExpiresAt: expirationTime,
+			Issuer:    username,
+		},
 	}
 
 	// Declare the token with the algorithm used for signing, and the claims
@@ -47,12 +53,25 @@ func GenerateToken(username string) (string, error) {
 	return tokenString, nil
 }
 
-func GenerateRandomKey() string {
-	key := make([]byte, 32) // generate a 256 bit key
-	_, err := rand.Read(key)
+// ParseToken validates a token issued by this service and returns its claims
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return JwtKey, nil
+	})
 	if err != nil {
-		panic("Failed to generate random key: " + err.Error())
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
 	}
+	return claims, nil
+}
 
+func GenerateRandomKey() string {
+	key := RandomBytes(32) // generate a 256 bit key
 	return base64.StdEncoding.EncodeToString(key)
 }
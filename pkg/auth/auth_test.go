@@ -25,3 +25,10 @@ func TestGenerateRandomKey(t *testing.T) {
 	assert.NotEmpty(t, randomKey)
 	assert.Len(t, randomKey, 44)
 }
+
+func TestRandomHex(t *testing.T) {
+	id := RandomHex(16)
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, RandomHex(16))
+	assert.Len(t, RandomURLSafe(32), 43)
+}
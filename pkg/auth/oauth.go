@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Scopes that can be granted to scope limited access tokens
+const (
+	ScopeBooksRead  = "books:read"
+	ScopeBooksWrite = "books:write"
+)
+
+// ClientTokenTTL is the lifetime of access tokens issued to OAuth2 clients
+const ClientTokenTTL = time.Hour
+
+var validScopes = map[string]bool{
+	ScopeBooksRead:  true,
+	ScopeBooksWrite: true,
+}
+
+// ValidScope reports whether scope is known to the service
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// HasScope reports whether the space separated scope string grants required
+func HasScope(scope string, required string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == required {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateClientToken issues a scope limited access token to an OAuth2 client
+func GenerateClientToken(clientID string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   clientID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ClientTokenTTL).Unix(),
+			Id:        RandomHex(16),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JwtKey)
+}
+
+// GenerateClientCredentials returns a new client ID and client secret pair
+func GenerateClientCredentials() (string, string) {
+	return RandomHex(16), RandomHex(32)
+}
+
+// HashToken hashes a high entropy secret such as a client secret for storage.
+// Unlike passwords these are random, so a fast hash is sufficient and lets
+// the hash be used for lookups.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateClientToken(t *testing.T) {
+	token, err := GenerateClientToken("client-1", []string{ScopeBooksRead})
+	assert.Nil(t, err)
+
+	claims, err := ParseToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "client-1", claims.ClientID)
+	assert.Equal(t, "client-1", claims.Subject)
+	assert.True(t, HasScope(claims.Scope, ScopeBooksRead))
+	assert.False(t, HasScope(claims.Scope, ScopeBooksWrite))
+}
+
+func TestParseTokenRejectsTamperedToken(t *testing.T) {
+	token, err := GenerateToken("jane")
+	assert.Nil(t, err)
+
+	claims, err := ParseToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "jane", claims.Username)
+
+	_, err = ParseToken(token + "x")
+	assert.NotNil(t, err)
+}
+
+func TestHashToken(t *testing.T) {
+	_, secret := GenerateClientCredentials()
+	assert.Len(t, HashToken(secret), 64)
+	assert.Equal(t, HashToken(secret), HashToken(secret))
+	assert.NotEqual(t, HashToken(secret), HashToken(secret+"x"))
+}
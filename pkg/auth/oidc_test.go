@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProvider is a minimal local OpenID Connect provider
+type stubProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	stub := &stubProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.server.URL,
+			"authorization_endpoint": stub.server.URL + "/authorize",
+			"token_endpoint":         stub.server.URL + "/token",
+			"jwks_uri":               stub.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || PKCEChallenge(r.Form.Get("code_verifier")) != stub.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": stub.sign(t), "token_type": "Bearer"})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+
+	stub.claims = jwt.MapClaims{
+		"iss":            stub.server.URL,
+		"sub":            "user-123",
+		"aud":            "client-id",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"email":          "jane@example.com",
+		"email_verified": true,
+	}
+	return stub
+}
+
+func (s *stubProvider) sign(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatalf("Failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+func (s *stubProvider) provider() *OIDCProvider {
+	return &OIDCProvider{
+		Name:        "stub",
+		Issuer:      s.server.URL,
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost:8001/api/v1/auth/oidc/stub/callback",
+		Scopes:      []string{"openid", "email"},
+	}
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.provider()
+	ctx := context.Background()
+
+	verifier := GeneratePKCEVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1", PKCEChallenge(verifier))
+	assert.Nil(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.Equal(t, "client-id", parsed.Query().Get("client_id"))
+	stub.challenge = parsed.Query().Get("code_challenge")
+	stub.claims["nonce"] = parsed.Query().Get("nonce")
+
+	_, err = provider.Exchange(ctx, "good-code", "wrong-verifier")
+	assert.NotNil(t, err)
+
+	tokenResponse, err := provider.Exchange(ctx, "good-code", verifier)
+	assert.Nil(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, tokenResponse.IDToken, "nonce-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	stub := newStubProvider(t)
+	provider := stub.provider()
+	ctx := context.Background()
+	stub.claims["nonce"] = "nonce-1"
+
+	_, err := provider.VerifyIDToken(ctx, stub.sign(t), "other-nonce")
+	assert.NotNil(t, err, "nonce mismatch must be rejected")
+
+	stub.claims["aud"] = []string{"another-client"}
+	_, err = provider.VerifyIDToken(ctx, stub.sign(t), "nonce-1")
+	assert.NotNil(t, err, "audience mismatch must be rejected")
+
+	stub.claims["aud"] = []string{"client-id"}
+	stub.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = provider.VerifyIDToken(ctx, stub.sign(t), "nonce-1")
+	assert.NotNil(t, err, "expired token must be rejected")
+
+	stub.claims["exp"] = time.Now().Add(time.Hour).Unix()
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, stub.claims)
+	forged.Header["kid"] = "test-key"
+	forgedToken, _ := forged.SignedString(otherKey)
+	_, err = provider.VerifyIDToken(ctx, forgedToken, "nonce-1")
+	assert.NotNil(t, err, "token signed with an unknown key must be rejected")
+
+	_, err = provider.VerifyIDToken(ctx, stub.sign(t), "nonce-1")
+	assert.Nil(t, err)
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	verifier := GeneratePKCEVerifier()
+	challenge := PKCEChallenge(verifier)
+	assert.Len(t, verifier, 43)
+	assert.Len(t, challenge, 43)
+	assert.Equal(t, challenge, PKCEChallenge(verifier))
+	assert.NotEqual(t, challenge, PKCEChallenge(GeneratePKCEVerifier()))
+	assert.NotContains(t, challenge, "=")
+}
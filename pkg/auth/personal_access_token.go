@@ -0,0 +1,9 @@
+package auth
+
+// PersonalAccessTokenPrefix distinguishes personal access tokens from JWTs
+const PersonalAccessTokenPrefix = "pat_"
+
+// GeneratePersonalAccessToken returns a new random personal access token
+func GeneratePersonalAccessToken() string {
+	return PersonalAccessTokenPrefix + RandomHex(32)
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// RandomBytes returns n bytes from the system's secure random source. It
+// panics if there are none, nothing random could be generated safely then.
+func RandomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("Failed to generate random value: " + err.Error())
+	}
+	return b
+}
+
+// RandomHex returns n random bytes as hex, for IDs and tokens
+func RandomHex(n int) string {
+	return hex.EncodeToString(RandomBytes(n))
+}
+
+// RandomURLSafe returns n random bytes as unpadded URL safe base64
+func RandomURLSafe(n int) string {
+	return base64.RawURLEncoding.EncodeToString(RandomBytes(n))
+}
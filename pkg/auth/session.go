@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"golang-rest-api-template/pkg/cache"
+	"os"
+	"time"
+)
+
+// Cookie and header names used by cookie based sessions
+const (
+	SessionCookieName = "session_id"
+	CSRFCookieName    = "csrf_token"
+	CSRFHeaderName    = "X-CSRF-Token"
+)
+
+// SessionTTL is the lifetime of a browser session
+const SessionTTL = 12 * time.Hour
+
+// Session is a server side browser session stored in Redis
+type Session struct {
+	ID        string `json:"-"`
+	Username  string `json:"username"`
+	CSRFToken string `json:"csrf_token"`
+}
+
+// SessionsEnabled reports whether /login should issue session cookies
+func SessionsEnabled() bool {
+	return os.Getenv("SESSION_COOKIES") == "true"
+}
+
+// sessionKey stores sessions under a hash so the cache never holds usable session IDs
+func sessionKey(id string) string {
+	return "session_" + HashToken(id)
+}
+
+// CreateSession starts a new session for username with its own CSRF token
+func CreateSession(ctx context.Context, redisClient cache.Cache, username string) (*Session, error) {
+	session := &Session{
+		ID:        RandomURLSafe(32),
+		Username:  username,
+		CSRFToken: RandomURLSafe(32),
+	}
+	serialized, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := redisClient.Set(ctx, sessionKey(session.ID), serialized, SessionTTL).Err(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// LoadSession returns the session for id, or an error if it expired or never existed
+func LoadSession(ctx context.Context, redisClient cache.Cache, id string) (*Session, error) {
+	serialized, err := redisClient.Get(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(serialized), &session); err != nil {
+		return nil, err
+	}
+	session.ID = id
+	return &session, nil
+}
+
+// DeleteSession ends the session
+func DeleteSession(ctx context.Context, redisClient cache.Cache, id string) error {
+	return redisClient.Del(ctx, sessionKey(id)).Err()
+}
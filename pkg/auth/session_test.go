@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/cache"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	ctx := context.Background()
+
+	var storedKey string
+	var storedValue []byte
+	mockCache.EXPECT().Set(ctx, gomock.Any(), gomock.Any(), SessionTTL).DoAndReturn(
+		func(_ context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+			storedKey, storedValue = key, value.([]byte)
+			return redis.NewStatusResult("OK", nil)
+		})
+
+	session, err := CreateSession(ctx, mockCache, "jane")
+	assert.Nil(t, err)
+	assert.NotEqual(t, session.ID, session.CSRFToken)
+	assert.NotContains(t, storedKey, session.ID, "the session ID must not be stored in clear")
+
+	mockCache.EXPECT().Get(ctx, storedKey).Return(redis.NewStringResult(string(storedValue), nil))
+	loaded, err := LoadSession(ctx, mockCache, session.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "jane", loaded.Username)
+	assert.Equal(t, session.CSRFToken, loaded.CSRFToken)
+
+	mockCache.EXPECT().Get(ctx, gomock.Any()).Return(redis.NewStringResult("", redis.Nil))
+	_, err = LoadSession(ctx, mockCache, "unknown")
+	assert.NotNil(t, err)
+}
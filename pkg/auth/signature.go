@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers used by the request signing scheme
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	ContentDigestHeader      = "X-Content-SHA256"
+)
+
+// MaxSignatureClockSkew is how far the signature timestamp may be from the server clock
+const MaxSignatureClockSkew = 5 * time.Minute
+
+// MaxSignedBodySize is the largest body of a signed request, it is read into
+// memory to compute its digest
+const MaxSignedBodySize = 10 << 20
+
+var (
+	ErrMissingSignature  = errors.New("missing or malformed signature")
+	ErrUnknownSigningKey = errors.New("unknown signing key")
+	ErrSignatureExpired  = errors.New("signature timestamp outside of the allowed window")
+	ErrDigestMismatch    = errors.New("body digest mismatch")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrBodyTooLarge      = fmt.Errorf("signed request body larger than %d MB", MaxSignedBodySize>>20)
+)
+
+// SignedRequest is the outcome of a successful signature verification
+type SignedRequest struct {
+	KeyID string
+	Nonce string
+}
+
+// LoadSigningKeys reads the per client secrets from API_SIGNING_KEYS, a comma
+// separated list of keyId:secret pairs.
+func LoadSigningKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("API_SIGNING_KEYS"), ",") {
+		keyID, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && keyID != "" && secret != "" {
+			keys[keyID] = secret
+		}
+	}
+	return keys
+}
+
+// BodyDigest returns the hex encoded SHA-256 digest of a request body
+func BodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// StringToSign builds the canonical representation of a request covered by
+// the signature: method, path and sorted query, timestamp, nonce, body digest
+// and the signed headers in the order they are listed.
+func StringToSign(req *http.Request, timestamp, nonce, bodyDigest string, signedHeaders []string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(req.Method))
+	b.WriteString("\n")
+	b.WriteString(req.URL.EscapedPath())
+	if query := req.URL.Query().Encode(); query != "" {
+		b.WriteString("?" + query)
+	}
+	b.WriteString("\n" + timestamp + "\n" + nonce + "\n" + bodyDigest)
+	for _, name := range signedHeaders {
+		name = strings.ToLower(name)
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+		}
+		b.WriteString("\n" + name + ":" + strings.TrimSpace(value))
+	}
+	return b.String()
+}
+
+// ComputeSignature returns the base64 encoded HMAC-SHA256 of stringToSign
+func ComputeSignature(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest signs req with the given key, setting the signature headers.
+// It is meant for clients of the API and for tests.
+func SignRequest(req *http.Request, keyID, secret string, signedHeaders []string) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := RandomHex(16)
+	digest := BodyDigest(body)
+
+	req.Header.Set(SignatureTimestampHeader, timestamp)
+	req.Header.Set(SignatureNonceHeader, nonce)
+	req.Header.Set(ContentDigestHeader, digest)
+
+	signature := ComputeSignature(secret, StringToSign(req, timestamp, nonce, digest, signedHeaders))
+	req.Header.Set(SignatureHeader, fmt.Sprintf(`keyId="%s",headers="%s",signature="%s"`,
+		keyID, strings.ToLower(strings.Join(signedHeaders, " ")), signature))
+	return nil
+}
+
+// VerifyRequest checks the signature of req against keys. It does not track
+// nonces, callers are responsible for rejecting replays of the returned nonce.
+func VerifyRequest(req *http.Request, keys map[string]string, now time.Time) (*SignedRequest, error) {
+	params, err := parseSignatureHeader(req.Header.Get(SignatureHeader))
+	if err != nil {
+		return nil, err
+	}
+
+	secret, ok := keys[params["keyId"]]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+
+	timestamp := req.Header.Get(SignatureTimestampHeader)
+	nonce := req.Header.Get(SignatureNonceHeader)
+	if timestamp == "" || nonce == "" {
+		return nil, ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrMissingSignature
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > MaxSignatureClockSkew || skew < -MaxSignatureClockSkew {
+		return nil, ErrSignatureExpired
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	digest := BodyDigest(body)
+	if claimed := req.Header.Get(ContentDigestHeader); claimed != "" && claimed != digest {
+		return nil, ErrDigestMismatch
+	}
+
+	expected := ComputeSignature(secret, StringToSign(req, timestamp, nonce, digest, strings.Fields(params["headers"])))
+	if !hmac.Equal([]byte(expected), []byte(params["signature"])) {
+		return nil, ErrInvalidSignature
+	}
+
+	return &SignedRequest{KeyID: params["keyId"], Nonce: nonce}, nil
+}
+
+// parseSignatureHeader parses keyId="...",headers="...",signature="..."
+func parseSignatureHeader(header string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, ErrMissingSignature
+		}
+		params[key] = strings.Trim(value, `"`)
+	}
+	if params["keyId"] == "" || params["signature"] == "" {
+		return nil, ErrMissingSignature
+	}
+	return params, nil
+}
+
+// readBody reads the request body and puts it back so handlers can read it
+// again. Bodies larger than MaxSignedBodySize fail with ErrBodyTooLarge.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxSignedBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxSignedBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
@@ -23,6 +24,10 @@ type Database interface {
 	First(dest interface{}, conds ...interface{}) Database
 	Updates(interface{}) *gorm.DB
 	Order(value interface{}) *gorm.DB
+	Scopes(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB
+	Raw(sql string, values ...interface{}) *gorm.DB
+	Transaction(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error
+	Dialect() string
 	Error() error
 }
 
@@ -42,6 +47,11 @@ func (db *GormDatabase) Error() error {
 	return db.DB.Error
 }
 
+// Dialect returns the name of the database dialect, e.g. postgres
+func (db *GormDatabase) Dialect() string {
+	return db.DB.Dialector.Name()
+}
+
 func NewDatabase() *gorm.DB {
 	var database *gorm.DB
 	var err error
@@ -55,7 +65,7 @@ func NewDatabase() *gorm.DB {
 	dbURl := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", db_user, db_pass, db_hostname, db_port, db_name)
 
 	for i := 1; i <= 3; i++ {
-		database, err = gorm.Open(postgres.Open(dbURl), &gorm.Config{})
+		database, err = gorm.Open(postgres.Open(dbURl), &gorm.Config{TranslateError: true})
 		if err == nil {
 			break
 		} else {
@@ -64,7 +74,20 @@ func NewDatabase() *gorm.DB {
 		}
 	}
 	database.AutoMigrate(&models.Book{})
+	database.AutoMigrate(&models.BookRevision{})
+	database.AutoMigrate(&models.Author{})
+	database.AutoMigrate(&models.BookAuthor{})
+	database.AutoMigrate(&models.Publisher{})
+	database.AutoMigrate(&models.Series{})
+	database.AutoMigrate(&models.Work{})
+	database.AutoMigrate(&models.ExportJob{})
 	database.AutoMigrate(&models.User{})
+	database.AutoMigrate(&models.UserIdentity{})
+	database.AutoMigrate(&models.OAuthClient{})
+	database.AutoMigrate(&models.PersonalAccessToken{})
+	if err := Migrate(database); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
 
 	return database
 }
@@ -5,6 +5,7 @@
 package database
 
 import (
+	sql "database/sql"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -67,6 +68,20 @@ func (mr *MockDatabaseMockRecorder) Delete(arg0 interface{}, arg1 ...interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDatabase)(nil).Delete), varargs...)
 }
 
+// Dialect mocks base method.
+func (m *MockDatabase) Dialect() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dialect")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Dialect indicates an expected call of Dialect.
+func (mr *MockDatabaseMockRecorder) Dialect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dialect", reflect.TypeOf((*MockDatabase)(nil).Dialect))
+}
+
 // Error mocks base method.
 func (m *MockDatabase) Error() error {
 	m.ctrl.T.Helper()
@@ -175,6 +190,62 @@ func (mr *MockDatabaseMockRecorder) Order(value interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Order", reflect.TypeOf((*MockDatabase)(nil).Order), value)
 }
 
+// Raw mocks base method.
+func (m *MockDatabase) Raw(sql string, values ...interface{}) *gorm.DB {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{sql}
+	for _, a := range values {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Raw", varargs...)
+	ret0, _ := ret[0].(*gorm.DB)
+	return ret0
+}
+
+// Raw indicates an expected call of Raw.
+func (mr *MockDatabaseMockRecorder) Raw(sql interface{}, values ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{sql}, values...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Raw", reflect.TypeOf((*MockDatabase)(nil).Raw), varargs...)
+}
+
+// Scopes mocks base method.
+func (m *MockDatabase) Scopes(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range funcs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Scopes", varargs...)
+	ret0, _ := ret[0].(*gorm.DB)
+	return ret0
+}
+
+// Scopes indicates an expected call of Scopes.
+func (mr *MockDatabaseMockRecorder) Scopes(funcs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scopes", reflect.TypeOf((*MockDatabase)(nil).Scopes), funcs...)
+}
+
+// Transaction mocks base method.
+func (m *MockDatabase) Transaction(fc func(*gorm.DB) error, opts ...*sql.TxOptions) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{fc}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transaction", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockDatabaseMockRecorder) Transaction(fc interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{fc}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDatabase)(nil).Transaction), varargs...)
+}
+
 // Updates mocks base method.
 func (m *MockDatabase) Updates(arg0 interface{}) *gorm.DB {
 	m.ctrl.T.Helper()
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// postgresMigrations create the schema AutoMigrate cannot express. They are
+// run in order on every start and must be idempotent.
+var postgresMigrations = []string{
+	// Full-text search over books, title matches weigh more than author matches
+	`ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(author, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector)`,
+	// Books without an ISBN-13 and books in the trash do not take part in its uniqueness
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn13 ON books (isbn13) WHERE isbn13 <> '' AND deleted_at IS NULL`,
+	// Trigram similarity of titles finds the candidates for duplicate books
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING GIN (title gin_trgm_ops) WHERE deleted_at IS NULL`,
+}
+
+// backfill is a one-time data migration
+type backfill struct {
+	name      string
+	statement string
+}
+
+// postgresBackfills fill in data for new schema from existing rows. Each one
+// runs once, in order, and is recorded in schema_migrations with the same
+// transaction.
+var postgresBackfills = []backfill{
+	// Authors for the free text bylines of books, named by their most frequent
+	// spelling. The key matches models.NameKey.
+	{"authors_from_bylines", `INSERT INTO authors (name, name_key, bio, created_at, updated_at)
+		SELECT DISTINCT ON (name_key) name, name_key, '', now(), now()
+		FROM (
+			SELECT trim(author) AS name, lower(regexp_replace(author, '[^[:alnum:]]+', '', 'g')) AS name_key, count(*) AS books
+			FROM books GROUP BY 1, 2
+		) spellings
+		WHERE name_key <> ''
+		ORDER BY name_key, books DESC, name
+		ON CONFLICT (name_key) DO NOTHING`},
+	// Books without credits are credited to the author of their byline
+	{"credits_from_bylines", `INSERT INTO books_authors (book_id, author_id, role, position)
+		SELECT books.id, authors.id, 'author', 0
+		FROM books JOIN authors ON authors.name_key = lower(regexp_replace(books.author, '[^[:alnum:]]+', '', 'g'))
+		WHERE NOT EXISTS (SELECT 1 FROM books_authors WHERE books_authors.book_id = books.id)
+		ON CONFLICT DO NOTHING`},
+	// Publishers for the publisher names of books, like authors for bylines
+	{"publishers_from_books", `INSERT INTO publishers (name, name_key, website, created_at, updated_at)
+		SELECT DISTINCT ON (name_key) name, name_key, '', now(), now()
+		FROM (
+			SELECT trim(publisher) AS name, lower(regexp_replace(publisher, '[^[:alnum:]]+', '', 'g')) AS name_key, count(*) AS books
+			FROM books GROUP BY 1, 2
+		) spellings
+		WHERE name_key <> ''
+		ORDER BY name_key, books DESC, name
+		ON CONFLICT (name_key) DO NOTHING`},
+	{"book_publisher_ids", `UPDATE books SET publisher_id = publishers.id
+		FROM publishers
+		WHERE books.publisher_id IS NULL AND publishers.name_key = lower(regexp_replace(books.publisher, '[^[:alnum:]]+', '', 'g'))`},
+	// Books without a work are grouped into works by title and byline, named
+	// after their oldest edition. IDs are drawn first so books can be linked
+	// in the same statement.
+	{"works_from_editions", `WITH editions AS (
+			SELECT id, title, lower(trim(title)) || '/' || lower(regexp_replace(author, '[^[:alnum:]]+', '', 'g')) AS work_key
+			FROM books WHERE work_id IS NULL
+		), new_works AS (
+			SELECT work_key, (array_agg(title ORDER BY id))[1] AS title, nextval(pg_get_serial_sequence('works', 'id')) AS id
+			FROM editions GROUP BY work_key
+		), created AS (
+			INSERT INTO works (id, title, created_at, updated_at) SELECT id, title, now(), now() FROM new_works
+		)
+		UPDATE books SET work_id = new_works.id
+		FROM editions JOIN new_works USING (work_key)
+		WHERE books.id = editions.id`},
+}
+
+// schemaMigrationsTable records the backfills that have run
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	name text PRIMARY KEY,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// Migrate runs the dialect specific migrations and the backfills that have
+// not run yet. It stops at the first one that fails.
+func Migrate(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for i, statement := range postgresMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	if err := db.Exec(schemaMigrationsTable).Error; err != nil {
+		return err
+	}
+	for _, backfill := range postgresBackfills {
+		if err := db.Transaction(backfill.run); err != nil {
+			return fmt.Errorf("backfill %s: %w", backfill.name, err)
+		}
+	}
+	return nil
+}
+
+// run runs the backfill unless it is recorded already. Recording it first
+// makes other instances starting at the same time wait and then skip it.
+func (b backfill) run(tx *gorm.DB) error {
+	result := tx.Exec(`INSERT INTO schema_migrations (name) VALUES (?) ON CONFLICT DO NOTHING`, b.name)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Exec(b.statement).Error
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestBackfillRunsOnce(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	recorded := false
+	var statements []string
+	db.Callback().Raw().After("gorm:raw").Register("test:statements", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		// Only the first run finds the backfill unrecorded
+		if !recorded {
+			recorded = true
+			tx.RowsAffected = 1
+		}
+	})
+	fill := backfill{name: "fill", statement: "UPDATE books SET genre = 'unknown' WHERE genre = ''"}
+
+	assert.NoError(t, fill.run(db))
+	assert.NoError(t, fill.run(db))
+
+	assert.Equal(t, []string{
+		"INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT DO NOTHING",
+		"UPDATE books SET genre = 'unknown' WHERE genre = ''",
+		"INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT DO NOTHING",
+	}, statements)
+}
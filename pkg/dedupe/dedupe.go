@@ -0,0 +1,191 @@
+// Package dedupe finds books that are likely duplicate records of each other.
+package dedupe
+
+import (
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/search"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Weights of the title and author similarity in the score of a pair
+const (
+	titleWeight  = 0.6
+	authorWeight = 0.4
+)
+
+// maxBlockSize skips title words shared by more books than this when looking
+// for candidates, they are too common to tell books apart
+const maxBlockSize = 200
+
+// stopWords are left out of titles, "The Hobbit" and "Hobbit" are the same book
+var stopWords = map[string]bool{"the": true, "a": true, "an": true, "of": true, "and": true}
+
+// Pair is a pair of books that may be duplicates, Book is the older one
+type Pair struct {
+	Book      models.Book `json:"book"`
+	Duplicate models.Book `json:"duplicate"`
+	// Score is 1 for books with the same ISBN-13, otherwise the weighted
+	// similarity of their titles and authors between 0 and 1
+	Score            float64 `json:"score"`
+	SameISBN         bool    `json:"same_isbn"`
+	TitleSimilarity  float64 `json:"title_similarity"`
+	AuthorSimilarity float64 `json:"author_similarity"`
+}
+
+// Compare scores a pair of books. Books with different ISBN-13s are different
+// editions, not duplicates, and score 0.
+func Compare(a, b models.Book) Pair {
+	if b.ID < a.ID {
+		a, b = b, a
+	}
+	pair := Pair{
+		Book:             a,
+		Duplicate:        b,
+		TitleSimilarity:  round(Similarity(titleWords(a.Title), titleWords(b.Title))),
+		AuthorSimilarity: round(Similarity(search.Normalize(a.Author), search.Normalize(b.Author))),
+	}
+	switch {
+	case a.ISBN13 != "" && a.ISBN13 == b.ISBN13:
+		pair.SameISBN = true
+		pair.Score = 1
+	case a.ISBN13 != "" && b.ISBN13 != "":
+		pair.Score = 0
+	default:
+		pair.Score = round(titleWeight*pair.TitleSimilarity + authorWeight*pair.AuthorSimilarity)
+	}
+	return pair
+}
+
+// Candidate is a pair of books worth comparing, by their IDs
+type Candidate struct {
+	BookID      uint
+	DuplicateID uint
+}
+
+// Find returns the pairs of books scoring at least threshold, best first.
+// Only books sharing an ISBN-13 or a title word are compared.
+func Find(books []models.Book, threshold float64) []Pair {
+	return Score(books, Candidates(books), threshold)
+}
+
+// Candidates returns the pairs of books sharing an ISBN-13 or a title word
+func Candidates(books []models.Book) []Candidate {
+	blocks := make(map[string][]int)
+	for i, book := range books {
+		if book.ISBN13 != "" {
+			blocks["isbn:"+book.ISBN13] = append(blocks["isbn:"+book.ISBN13], i)
+		}
+		seen := make(map[string]bool)
+		for _, word := range strings.Fields(titleWords(book.Title)) {
+			if !seen[word] {
+				seen[word] = true
+				blocks[word] = append(blocks[word], i)
+			}
+		}
+	}
+
+	compared := make(map[Candidate]bool)
+	var candidates []Candidate
+	for _, block := range blocks {
+		if len(block) > maxBlockSize {
+			continue
+		}
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				candidate := Candidate{books[block[x]].ID, books[block[y]].ID}
+				if !compared[candidate] {
+					compared[candidate] = true
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// Score compares the candidate pairs among books and returns those scoring
+// at least threshold, best first. Candidates naming books that are not
+// among books are skipped.
+func Score(books []models.Book, candidates []Candidate, threshold float64) []Pair {
+	byID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+	var pairs []Pair
+	for _, candidate := range candidates {
+		a, okA := byID[candidate.BookID]
+		b, okB := byID[candidate.DuplicateID]
+		if !okA || !okB {
+			continue
+		}
+		if pair := Compare(a, b); pair.Score >= threshold {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		if pairs[i].Book.ID != pairs[j].Book.ID {
+			return pairs[i].Book.ID < pairs[j].Book.ID
+		}
+		return pairs[i].Duplicate.ID < pairs[j].Duplicate.ID
+	})
+	return pairs
+}
+
+// Similarity is the Dice coefficient of the trigrams of the words of two
+// normalized texts. Word order does not matter, so "Tolkien J R R" and
+// "J R R Tolkien" are equal.
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	trigramsA, trigramsB := trigrams(a), trigrams(b)
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(trigramsA))
+	for _, trigram := range trigramsA {
+		counts[trigram]++
+	}
+	shared := 0
+	for _, trigram := range trigramsB {
+		if counts[trigram] > 0 {
+			counts[trigram]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(trigramsA)+len(trigramsB))
+}
+
+// titleWords normalizes a title and drops its stop words
+func titleWords(title string) string {
+	var words []string
+	for _, word := range strings.Fields(search.Normalize(title)) {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// trigrams returns the trigrams of the words of normalized text, padded so
+// that short words have some too
+func trigrams(normalized string) []string {
+	var trigrams []string
+	for _, word := range strings.Fields(normalized) {
+		runes := []rune("  " + word + " ")
+		for k := 0; k+3 <= len(runes); k++ {
+			trigrams = append(trigrams, string(runes[k:k+3]))
+		}
+	}
+	return trigrams
+}
+
+func round(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
@@ -0,0 +1,68 @@
+package dedupe
+
+import (
+	"golang-rest-api-template/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("hobbit", "hobbit"))
+	assert.Equal(t, 1.0, Similarity("tolkien j r r", "j r r tolkien"))
+	assert.Equal(t, 0.0, Similarity("", "hobbit"))
+	assert.Greater(t, Similarity("hobbit", "hobit"), 0.7)
+	assert.Less(t, Similarity("hobbit", "dune"), 0.2)
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     models.Book
+		sameISBN bool
+		min, max float64
+	}{
+		{"same ISBN", models.Book{ID: 1, Title: "Dune", ISBN13: "9780441172719"}, models.Book{ID: 2, Title: "Children of Dune", ISBN13: "9780441172719"}, true, 1, 1},
+		{"other ISBN", models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", ISBN13: "9780441172719"}, models.Book{ID: 2, Title: "Dune", Author: "Frank Herbert", ISBN13: "9780340960196"}, false, 0, 0},
+		{"article and typo", models.Book{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"}, models.Book{ID: 2, Title: "Hobit", Author: "Tolkien, J. R. R."}, false, 0.8, 0.95},
+		{"other book", models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert"}, models.Book{ID: 2, Title: "Dune Messiah", Author: "Frank Herbert"}, false, 0.5, 0.8},
+	}
+
+	for _, tt := range tests {
+		pair := Compare(tt.b, tt.a)
+		assert.Equal(t, uint(1), pair.Book.ID, tt.name)
+		assert.Equal(t, tt.sameISBN, pair.SameISBN, tt.name)
+		assert.GreaterOrEqual(t, pair.Score, tt.min, tt.name)
+		assert.LessOrEqual(t, pair.Score, tt.max, tt.name)
+	}
+}
+
+func TestFind(t *testing.T) {
+	books := []models.Book{
+		{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"},
+		{ID: 2, Title: "Dune", Author: "Frank Herbert", ISBN13: "9780441172719"},
+		{ID: 3, Title: "Hobbit", Author: "J. R. R. Tolkien"},
+		{ID: 4, Title: "Dune (Ace)", Author: "Herbert", ISBN13: "9780441172719"},
+		{ID: 5, Title: "The Silmarillion", Author: "J.R.R. Tolkien"},
+	}
+
+	pairs := Find(books, 0.8)
+	if assert.Len(t, pairs, 2) {
+		assert.Equal(t, [2]uint{1, 3}, [2]uint{pairs[0].Book.ID, pairs[0].Duplicate.ID})
+		assert.Equal(t, [2]uint{2, 4}, [2]uint{pairs[1].Book.ID, pairs[1].Duplicate.ID})
+		assert.True(t, pairs[1].SameISBN)
+	}
+}
+
+func TestScore(t *testing.T) {
+	books := []models.Book{
+		{ID: 1, Title: "The Hobbit", Author: "J.R.R. Tolkien"},
+		{ID: 3, Title: "Hobbit", Author: "J. R. R. Tolkien"},
+		{ID: 5, Title: "The Silmarillion", Author: "J.R.R. Tolkien"},
+	}
+
+	pairs := Score(books, []Candidate{{3, 1}, {1, 5}, {1, 9}}, 0.8)
+	if assert.Len(t, pairs, 1) {
+		assert.Equal(t, [2]uint{1, 3}, [2]uint{pairs[0].Book.ID, pairs[0].Duplicate.ID})
+	}
+}
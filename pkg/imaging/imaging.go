@@ -0,0 +1,104 @@
+// Package imaging checks uploaded images and scales them down to thumbnails.
+// JPEG and PNG are decoded by the standard library, WebP by golang.org/x/image.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Content types of the images that are accepted
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	WebP = "image/webp"
+)
+
+// Errors returned for images that are not accepted
+var (
+	ErrUnsupported = errors.New("image must be a JPEG, PNG or WebP file")
+	ErrCorrupt     = errors.New("image is corrupt")
+)
+
+// Sniff returns the content type of an image from its first bytes, whatever
+// the client claims it to be
+func Sniff(data []byte) (string, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case JPEG, PNG, WebP:
+		return contentType, nil
+	}
+	return "", ErrUnsupported
+}
+
+// Size reads the dimensions of an image from its header, without decoding
+// its pixels
+func Size(data []byte) (width, height int, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, ErrCorrupt
+	}
+	return config.Width, config.Height, nil
+}
+
+// Decode decodes a JPEG, PNG or WebP image
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return img, nil
+}
+
+// Thumbnail scales img down to at most width pixels wide, keeping its aspect
+// ratio, by averaging the pixels each thumbnail pixel covers. Images are
+// never scaled up. Transparent parts become white, so the thumbnail can be
+// encoded as JPEG.
+func Thumbnail(img image.Image, width int) *image.RGBA {
+	src := flatten(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= width {
+		return src
+	}
+	height := max(1, (sh*width+sw/2)/sw)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[4*sx])
+					g += int(row[4*sx+1])
+					b += int(row[4*sx+2])
+					n++
+				}
+			}
+			offset := y*dst.Stride + 4*x
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = 0xff
+		}
+	}
+	return dst
+}
+
+// flatten draws img over a white background into an RGBA image starting at the origin
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
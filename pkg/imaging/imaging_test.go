@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encoded(t *testing.T, img image.Image, encode func(*bytes.Buffer, image.Image) error) []byte {
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 60))
+	pngData := encoded(t, img, func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) })
+	jpegData := encoded(t, img, func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) })
+	// Lossless WebP of a single pixel
+	webpData, _ := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+	tests := []struct {
+		data          []byte
+		contentType   string
+		err           error
+		width, height int
+	}{
+		{pngData, PNG, nil, 40, 60},
+		{jpegData, JPEG, nil, 40, 60},
+		{webpData, WebP, nil, 1, 1},
+		{[]byte("GIF89a"), "", ErrUnsupported, 0, 0},
+		{[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), "", ErrUnsupported, 0, 0},
+	}
+
+	for _, tt := range tests {
+		contentType, err := Sniff(tt.data)
+		assert.Equal(t, tt.contentType, contentType)
+		assert.Equal(t, tt.err, err)
+		if err == nil {
+			width, height, err := Size(tt.data)
+			assert.NoError(t, err, contentType)
+			assert.Equal(t, [2]int{tt.width, tt.height}, [2]int{width, height}, contentType)
+		}
+	}
+
+	_, _, err := Size(pngData[:20])
+	assert.Equal(t, ErrCorrupt, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	// Left half red, right half transparent
+	img := image.NewNRGBA(image.Rect(10, 10, 410, 610))
+	for y := 10; y < 610; y++ {
+		for x := 10; x < 210; x++ {
+			img.Set(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+
+	thumb := Thumbnail(img, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 150), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, thumb.RGBAAt(10, 10))
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, thumb.RGBAAt(90, 140))
+
+	// Small images are not scaled up
+	assert.Equal(t, image.Rect(0, 0, 400, 600), Thumbnail(img, 1000).Bounds())
+}
@@ -0,0 +1,94 @@
+// Package isbn validates and converts International Standard Book Numbers.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrFormat is returned for ISBNs with the wrong number of digits or other characters than digits
+	ErrFormat = errors.New("invalid format")
+	// ErrChecksum is returned for ISBNs whose check digit does not match
+	ErrChecksum = errors.New("invalid check digit")
+)
+
+// Clean removes the hyphens and spaces ISBNs are often written with and
+// uppercases the X check digit of an ISBN-10
+func Clean(s string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+}
+
+// Normalize10 cleans s and checks that it is a valid ISBN-10
+func Normalize10(s string) (string, error) {
+	s = Clean(s)
+	if len(s) != 10 || !digits(s[:9]) || !(digits(s[9:]) || s[9] == 'X') {
+		return "", ErrFormat
+	}
+	if check10(s[:9]) != s[9] {
+		return "", ErrChecksum
+	}
+	return s, nil
+}
+
+// Normalize13 cleans s and checks that it is a valid ISBN-13
+func Normalize13(s string) (string, error) {
+	s = Clean(s)
+	if len(s) != 13 || !digits(s) || !(strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) {
+		return "", ErrFormat
+	}
+	if check13(s[:12]) != s[12] {
+		return "", ErrChecksum
+	}
+	return s, nil
+}
+
+// To13 converts a normalized ISBN-10 to its ISBN-13
+func To13(isbn10 string) string {
+	prefix := "978" + isbn10[:9]
+	return prefix + string(check13(prefix))
+}
+
+// To10 converts a normalized ISBN-13 to its ISBN-10. Only ISBN-13s with the
+// 978 prefix have one.
+func To10(isbn13 string) (string, bool) {
+	if !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	return isbn13[3:12] + string(check10(isbn13[3:12])), true
+}
+
+// check10 computes the check digit of the first nine digits of an ISBN-10
+func check10(s string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(s[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// check13 computes the check digit of the first twelve digits of an ISBN-13
+func check13(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(s[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize10(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"0-441-17271-7", "0441172717", nil},
+		{" 0 306 40615 2 ", "0306406152", nil},
+		{"080442957x", "080442957X", nil},
+		{"0441172718", "", ErrChecksum},
+		{"044117271", "", ErrFormat},
+		{"04411727A7", "", ErrFormat},
+		{"978-0-441-17271-9", "", ErrFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize10(tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+		assert.Equal(t, tt.err, err, tt.input)
+	}
+}
+
+func TestNormalize13(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"978-0-441-17271-9", "9780441172719", nil},
+		{"979-10-90636-07-1", "9791090636071", nil},
+		{"9780441172710", "", ErrChecksum},
+		{"9770441172719", "", ErrFormat},
+		{"0441172717", "", ErrFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize13(tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+		assert.Equal(t, tt.err, err, tt.input)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, "9780441172719", To13("0441172717"))
+	assert.Equal(t, "9780804429573", To13("080442957X"))
+
+	isbn10, ok := To10("9780804429573")
+	assert.True(t, ok)
+	assert.Equal(t, "080442957X", isbn10)
+
+	_, ok = To10("9791090636071")
+	assert.False(t, ok)
+}
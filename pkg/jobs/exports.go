@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/models"
+	"io/fs"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PurgeExports deletes the files and jobs of the exports that expired before
+// now and returns their number
+func PurgeExports(db *gorm.DB, now time.Time) (int64, error) {
+	var expired []models.ExportJob
+	if err := db.Where("expires_at < ?", now).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, job := range expired {
+		// The job is kept if its file could not be removed, to try again later
+		if job.Path != "" {
+			if err := os.Remove(job.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return purged, err
+			}
+		}
+		if err := db.Delete(&models.ExportJob{}, "id = ?", job.ID).Error; err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartExportCleaner purges expired exports right away and then every
+// interval until ctx is done
+func StartExportCleaner(ctx context.Context, db *gorm.DB, logger *zap.Logger, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeExports(db.WithContext(ctx), time.Now())
+			if err != nil {
+				logger.Error("Failed to purge expired exports", zap.Error(err))
+			} else if purged > 0 {
+				logger.Info("Purged expired exports", zap.Int64("count", purged))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"golang-rest-api-template/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestPurgeExports(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	// One expired export has a file, the other one failed without writing any
+	path := filepath.Join(t.TempDir(), "abc.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("id\n"), 0o600))
+	var query string
+	db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		query = tx.Statement.SQL.String()
+		*tx.Statement.Dest.(*[]models.ExportJob) = []models.ExportJob{{ID: "abc", Path: path}, {ID: "def"}}
+	})
+	var deletes []string
+	db.Callback().Delete().After("gorm:delete").Register("test:sql", func(tx *gorm.DB) {
+		deletes = append(deletes, tx.Statement.SQL.String())
+	})
+
+	purged, err := PurgeExports(db, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	assert.Equal(t, `SELECT * FROM "export_jobs" WHERE expires_at < $1`, query)
+	assert.Equal(t, []string{`DELETE FROM "export_jobs" WHERE id = $1`, `DELETE FROM "export_jobs" WHERE id = $1`}, deletes)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PurgeImportReports deletes the files in dir last written before cutoff,
+// import reports as well as the leftovers of imports that crashed, and
+// returns their number
+func PurgeImportReports(dir string, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) || entry.IsDir() {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartImportReportCleaner purges import reports older than ttl right away
+// and then every interval until ctx is done
+func StartImportReportCleaner(ctx context.Context, dir string, logger *zap.Logger, ttl, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeImportReports(dir, time.Now().Add(-ttl))
+			if err != nil {
+				logger.Error("Failed to purge import reports", zap.Error(err))
+			} else if purged > 0 {
+				logger.Info("Purged import reports", zap.Int("count", purged))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeImportReports(t *testing.T) {
+	dir := t.TempDir()
+	old, recent := filepath.Join(dir, "old.csv"), filepath.Join(dir, "recent.csv")
+	assert.NoError(t, os.WriteFile(old, []byte("line\n"), 0o600))
+	assert.NoError(t, os.WriteFile(recent, []byte("line\n"), 0o600))
+	cutoff := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(old, cutoff.Add(-time.Minute), cutoff.Add(-time.Minute)))
+
+	purged, err := PurgeImportReports(dir, cutoff)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(recent)
+	assert.NoError(t, err)
+
+	// No imports have run yet
+	purged, err = PurgeImportReports(filepath.Join(dir, "missing"), cutoff)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+}
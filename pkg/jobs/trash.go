@@ -0,0 +1,87 @@
+// Package jobs runs periodic maintenance in the background.
+package jobs
+
+import (
+	"context"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/storage"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultTrashRetentionDays is how long deleted books are kept if TRASH_RETENTION_DAYS is not set
+const defaultTrashRetentionDays = 30
+
+// TrashRetention returns how long deleted books are kept before they are
+// purged, or zero if they are kept forever
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Invalid TRASH_RETENTION_DAYS %q, keeping deleted books for %d days", value, days)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeTrash permanently deletes the books deleted before cutoff and returns their number
+func PurgeTrash(ctx context.Context, db *gorm.DB, blobs storage.BlobStore, cutoff time.Time) (int64, error) {
+	return PurgeBooks(ctx, db.Where("deleted_at < ?", cutoff), blobs)
+}
+
+// PurgeBooks permanently deletes the books db selects and their cover images
+// and returns their number. Images that fail to delete are only logged.
+func PurgeBooks(ctx context.Context, db *gorm.DB, blobs storage.BlobStore) (int64, error) {
+	var purged []models.Book
+	result := db.Unscoped().
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "cover_keys"}}}).
+		Delete(&purged)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	for _, book := range purged {
+		for _, key := range book.CoverKeys {
+			if err := blobs.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete cover image %s of purged book %d: %v", key, book.ID, err)
+			}
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// StartTrashPurger purges books and their covers that have been in the trash for longer than
+// retention, right away and then every interval until ctx is done. It does
+// nothing if retention is zero.
+func StartTrashPurger(ctx context.Context, db *gorm.DB, blobs storage.BlobStore, logger *zap.Logger, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeTrash(ctx, db.WithContext(ctx), blobs, time.Now().Add(-retention))
+			if err != nil {
+				logger.Error("Failed to purge trashed books", zap.Error(err))
+			} else if purged > 0 {
+				logger.Info("Purged trashed books", zap.Int64("count", purged))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
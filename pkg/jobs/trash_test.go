@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/models"
+	"golang-rest-api-template/pkg/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestTrashRetention(t *testing.T) {
+	t.Setenv("TRASH_RETENTION_DAYS", "")
+	assert.Equal(t, 30*24*time.Hour, TrashRetention())
+
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+	assert.Equal(t, 7*24*time.Hour, TrashRetention())
+
+	t.Setenv("TRASH_RETENTION_DAYS", "0")
+	assert.Equal(t, time.Duration(0), TrashRetention())
+
+	t.Setenv("TRASH_RETENTION_DAYS", "-1")
+	assert.Equal(t, 30*24*time.Hour, TrashRetention())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	var sql string
+	db.Callback().Delete().After("gorm:delete").Register("test:sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		*tx.Statement.Dest.(*[]models.Book) = []models.Book{
+			{ID: 1, CoverKeys: map[string]string{"original": "covers/1/ab/original.jpg"}},
+			{ID: 2},
+		}
+		tx.RowsAffected = 2
+	})
+	ctx := context.Background()
+	blobs := storage.NewLocalStore(t.TempDir())
+	assert.NoError(t, blobs.Put(ctx, "covers/1/ab/original.jpg", strings.NewReader("cover")))
+
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	purged, err := PurgeTrash(ctx, db, blobs, cutoff)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	assert.Equal(t, `DELETE FROM "books" WHERE deleted_at < $1 RETURNING "id","cover_keys"`, sql)
+	// The covers of purged books go with them
+	_, _, err = blobs.Open(ctx, "covers/1/ab/original.jpg")
+	assert.True(t, errors.Is(err, storage.ErrNotFound), err)
+}
@@ -2,15 +2,26 @@ package middleware
 
 import (
 	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
-func JWTAuth() gin.HandlerFunc {
+// lastUsedResolution limits how often the last used time of a personal access token is written
+const lastUsedResolution = time.Minute
+
+func JWTAuth(db database.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Already authenticated by a session cookie
+		if _, ok := c.Get("session"); ok {
+			c.Next()
+			return
+		}
+
 		const BearerSchema = "Bearer "
 		header := c.GetHeader("Authorization")
 		if header == "" {
@@ -26,25 +37,81 @@ func JWTAuth() gin.HandlerFunc {
 		}
 
 		tokenStr := header[len(BearerSchema):]
-		claims := &auth.Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return auth.JwtKey, nil
-		})
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		if strings.HasPrefix(tokenStr, auth.PersonalAccessTokenPrefix) {
+			personalAccessTokenAuth(c, db, tokenStr)
 			return
 		}
 
-		if !token.Valid {
+		claims, err := auth.ParseToken(tokenStr)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("username", claims.Username)
+		// Tokens issued to OAuth2 clients are limited to their granted scopes
+		// and stop working when the client is revoked
+		if claims.ClientID != "" {
+			var client models.OAuthClient
+			if err := db.Where("client_id = ?", claims.ClientID).First(&client).Error(); err != nil || client.RevokedAt != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired or revoked"})
+				c.Abort()
+				return
+			}
+			c.Set("client_id", claims.ClientID)
+			c.Set("scopes", claims.Scope)
+		} else {
+			c.Set("username", claims.Username)
+		}
 		c.Next()
 	}
 }
+
+// personalAccessTokenAuth authenticates the owner of a personal access token,
+// limiting the request to the scopes of the token.
+func personalAccessTokenAuth(c *gin.Context, db database.Database, tokenStr string) {
+	var token models.PersonalAccessToken
+	var user models.User
+
+	if err := db.Where("token_hash = ?", auth.HashToken(tokenStr)).First(&token).Error(); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired or revoked"})
+		c.Abort()
+		return
+	}
+
+	if err := db.Where("id = ?", token.UserID).First(&user).Error(); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		db.Model(&token).Update("last_used_at", now)
+	}
+
+	c.Set("username", user.Username)
+	c.Set("scopes", token.Scopes)
+	c.Set("token_id", token.ID)
+	c.Next()
+}
+
+// OptionalJWTAuth authenticates requests that carry a token or session like
+// JWTAuth and lets anonymous ones through, so that routes open to everyone
+// can still hold tokens to their scopes
+func OptionalJWTAuth(db database.Database) gin.HandlerFunc {
+	jwtAuth := JWTAuth(db)
+	return func(c *gin.Context) {
+		if _, ok := c.Get("session"); !ok && c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		jwtAuth(c)
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB records the statements it would run instead of running them
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	return db
+}
+
+func bearerRequest(token string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestJWTAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	var username string
+	handlers := []gin.HandlerFunc{JWTAuth(mockDB), func(c *gin.Context) { username = c.GetString("username") }}
+
+	token, err := auth.GenerateToken("jane")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(bearerRequest(token), handlers...).Code)
+	assert.Equal(t, "jane", username)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(req, handlers...).Code)
+
+	req.Header.Set("Authorization", "Basic amFuZTpzZWNyZXQ=")
+	assert.Equal(t, http.StatusUnauthorized, serve(req, handlers...).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(bearerRequest(token+"x"), handlers...).Code)
+}
+
+func TestJWTAuthClientToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	var scopes string
+	handlers := []gin.HandlerFunc{JWTAuth(mockDB), func(c *gin.Context) { scopes = c.GetString("scopes") }}
+	token, err := auth.GenerateClientToken("client-1", []string{auth.ScopeBooksRead})
+	assert.NoError(t, err)
+
+	expectClient := func(client models.OAuthClient) {
+		mockDB.EXPECT().Where("client_id = ?", "client-1").Return(mockDB)
+		mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+			*dest.(*models.OAuthClient) = client
+			return mockDB
+		})
+		mockDB.EXPECT().Error().Return(nil)
+	}
+
+	expectClient(models.OAuthClient{ClientID: "client-1"})
+	assert.Equal(t, http.StatusOK, serve(bearerRequest(token), handlers...).Code)
+	assert.Equal(t, auth.ScopeBooksRead, scopes)
+
+	// Revoking the client ends its tokens before they expire
+	revokedAt := time.Now()
+	expectClient(models.OAuthClient{ClientID: "client-1", RevokedAt: &revokedAt})
+	assert.Equal(t, http.StatusUnauthorized, serve(bearerRequest(token), handlers...).Code)
+}
+
+func TestOptionalJWTAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := database.NewMockDatabase(ctrl)
+	handlers := []gin.HandlerFunc{OptionalJWTAuth(mockDB), RequireScope(auth.ScopeBooksRead)}
+
+	// Anonymous reads and user tokens pass
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	assert.Equal(t, http.StatusOK, serve(req, handlers...).Code)
+	token, err := auth.GenerateToken("jane")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(bearerRequest(token), handlers...).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(bearerRequest("garbage"), handlers...).Code)
+
+	// A write only client token is held to its scope
+	mockDB.EXPECT().Where("client_id = ?", "client-1").Return(mockDB)
+	mockDB.EXPECT().First(gomock.Any()).Return(mockDB)
+	mockDB.EXPECT().Error().Return(nil)
+	token, err = auth.GenerateClientToken("client-1", []string{auth.ScopeBooksWrite})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, serve(bearerRequest(token), handlers...).Code)
+}
+
+func TestJWTAuthPersonalAccessToken(t *testing.T) {
+	now := time.Now()
+	recently, longAgo, past := now.Add(-10*time.Second), now.Add(-time.Hour), now.Add(-time.Minute)
+	tests := []struct {
+		name    string
+		token   models.PersonalAccessToken
+		status  int
+		touched bool
+	}{
+		{"valid", models.PersonalAccessToken{ExpiresAt: now.Add(time.Hour), LastUsedAt: &longAgo}, http.StatusOK, true},
+		{"never used", models.PersonalAccessToken{ExpiresAt: now.Add(time.Hour)}, http.StatusOK, true},
+		{"used within a minute", models.PersonalAccessToken{ExpiresAt: now.Add(time.Hour), LastUsedAt: &recently}, http.StatusOK, false},
+		{"expired", models.PersonalAccessToken{ExpiresAt: past}, http.StatusUnauthorized, false},
+		{"revoked", models.PersonalAccessToken{ExpiresAt: now.Add(time.Hour), RevokedAt: &past}, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		secret := auth.GeneratePersonalAccessToken()
+
+		token := tt.token
+		token.ID, token.UserID, token.Scopes = 3, 7, auth.ScopeBooksRead
+		mockDB.EXPECT().Where("token_hash = ?", auth.HashToken(secret)).Return(mockDB)
+		mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+			*dest.(*models.PersonalAccessToken) = token
+			return mockDB
+		})
+		mockDB.EXPECT().Error().Return(nil)
+		if tt.status == http.StatusOK {
+			mockDB.EXPECT().Where("id = ?", uint(7)).Return(mockDB)
+			mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+				*dest.(*models.User) = models.User{ID: 7, Username: "jane"}
+				return mockDB
+			})
+			mockDB.EXPECT().Error().Return(nil)
+		}
+
+		touched := false
+		db := dryRunDB(t)
+		db.Callback().Update().After("gorm:update").Register("test:touched", func(tx *gorm.DB) {
+			touched = true
+		})
+		if tt.touched {
+			mockDB.EXPECT().Model(gomock.Any()).DoAndReturn(db.Model)
+		}
+
+		var scopes string
+		w := serve(bearerRequest(secret), JWTAuth(mockDB), func(c *gin.Context) { scopes = c.GetString("scopes") })
+
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		assert.Equal(t, tt.touched, touched, tt.name)
+		if tt.status == http.StatusOK {
+			// The request is limited to the scopes of the token
+			assert.Equal(t, auth.ScopeBooksRead, scopes, tt.name)
+		}
+		ctrl.Finish()
+	}
+}
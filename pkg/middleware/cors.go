@@ -15,8 +15,9 @@ func Cors() gin.HandlerFunc {
 			"http://localhost",
 			"http://localhost:8001"},
 		AllowMethods: []string{"*"},
-		AllowHeaders: []string{"*"},
-		//ExposeHeaders:    []string{"Content-Length"},
+		// Browsers don't expand "*" for credentialed requests, so the headers clients set are listed explicitly
+		AllowHeaders:     []string{"*", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token", "If-Match", "If-None-Match", "If-Modified-Since", RequestIDHeader},
+		ExposeHeaders:    []string{"ETag", "Link", RequestIDHeader},
 		AllowCredentials: true,
 		//AllowOriginFunc: func(origin string) bool {
 		//	return origin == "https://github.com"
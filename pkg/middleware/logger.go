@@ -29,6 +29,7 @@ func Logger(logger *zap.Logger, collection *mongo.Collection) gin.HandlerFunc {
 			zap.Duration("duration", duration),
 			zap.String("ip", c.ClientIP()),
 			zap.String("user-agent", c.Request.UserAgent()),
+			zap.String("request-id", c.GetString("request_id")),
 			zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
 		)
 
@@ -39,6 +40,7 @@ func Logger(logger *zap.Logger, collection *mongo.Collection) gin.HandlerFunc {
 			"duration":   duration,
 			"ip":         c.ClientIP(),
 			"user-agent": c.Request.UserAgent(),
+			"request-id": c.GetString("request_id"),
 			"errors":     c.Errors.ByType(gin.ErrorTypePrivate).String(),
 		}
 
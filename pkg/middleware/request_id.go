@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs passed in by clients or proxies
+const maxRequestIDLength = 128
+
+// RequestID keeps the X-Request-ID a proxy or client sent, or generates one,
+// and echoes it in the response. Handlers find it as request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = auth.RandomHex(16)
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole must run after JWTAuth and only lets users with one of roles
+// through. The role is looked up on every request so that revoking it takes
+// effect immediately. Client credentials tokens have no user and are rejected.
+func RequireRole(db database.Database, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+
+		username := c.GetString("username")
+		if username == "" || db.Where("username = ?", username).First(&user).Error() != nil || !hasRole(user, roles) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasRole(user models.User, roles []string) bool {
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"golang-rest-api-template/pkg/database"
+	"golang-rest-api-template/pkg/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		role     string
+		err      error
+		status   int
+	}{
+		{"admin", "jane", models.RoleAdmin, nil, http.StatusOK},
+		{"librarian", "jane", models.RoleLibrarian, nil, http.StatusOK},
+		{"user", "jane", models.RoleUser, nil, http.StatusForbidden},
+		{"unknown user", "jane", "", errors.New("record not found"), http.StatusForbidden},
+		{"client token", "", "", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		ctrl := gomock.NewController(t)
+		mockDB := database.NewMockDatabase(ctrl)
+		if tt.username != "" {
+			mockDB.EXPECT().Where("username = ?", tt.username).Return(mockDB)
+			mockDB.EXPECT().First(gomock.Any()).DoAndReturn(func(dest interface{}, conds ...interface{}) database.Database {
+				*dest.(*models.User) = models.User{Username: tt.username, Role: tt.role}
+				return mockDB
+			})
+			mockDB.EXPECT().Error().Return(tt.err)
+		}
+
+		authenticate := func(c *gin.Context) {
+			if tt.username != "" {
+				c.Set("username", tt.username)
+			}
+		}
+		req, _ := http.NewRequest(http.MethodDelete, "/api/v1/books/trash/1", nil)
+		w := serve(req, authenticate, RequireRole(mockDB, models.RoleLibrarian, models.RoleAdmin))
+
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		ctrl.Finish()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope must run after JWTAuth. User tokens carry no scopes and are
+// not restricted, scope limited tokens must have been granted scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, limited := c.Get("scopes")
+		if limited && !auth.HasScope(granted.(string), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
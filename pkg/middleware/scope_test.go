@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/auth"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes *string
+		status int
+	}{
+		{"user token", nil, http.StatusOK},
+		{"granted", stringPtr(auth.ScopeBooksRead + " " + auth.ScopeBooksWrite), http.StatusOK},
+		{"not granted", stringPtr(auth.ScopeBooksRead), http.StatusForbidden},
+		{"no scopes", stringPtr(""), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		authenticate := func(c *gin.Context) {
+			if tt.scopes != nil {
+				c.Set("scopes", *tt.scopes)
+			}
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/books", nil)
+		assert.Equal(t, tt.status, serve(req, authenticate, RequireScope(auth.ScopeBooksWrite)).Code, tt.name)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Session resolves the session cookie to the logged in user. Requests that
+// send an Authorization header are left to JWTAuth.
+func Session(redisClient cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, err := c.Cookie(auth.SessionCookieName)
+		if err != nil || sessionID == "" || c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		session, err := auth.LoadSession(context.Background(), redisClient, sessionID)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Set("session", session)
+		c.Set("username", session.Username)
+		c.Next()
+	}
+}
+
+// CSRF protects state changing requests authenticated by a session cookie.
+// The X-CSRF-Token header must match both the csrf_token cookie (double
+// submit) and the token stored with the session (synchronizer token).
+func CSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("session")
+		if !ok || isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		session := value.(*auth.Session)
+
+		header := c.GetHeader(auth.CSRFHeaderName)
+		cookie, _ := c.Cookie(auth.CSRFCookieName)
+		if header == "" ||
+			subtle.ConstantTimeCompare([]byte(header), []byte(cookie)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(header), []byte(session.CSRFToken)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
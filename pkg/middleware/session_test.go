@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func sessionRequest(method, csrfCookie, csrfHeader string) *http.Request {
+	req, _ := http.NewRequest(method, "/api/v1/books", nil)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: "session-1"})
+	if csrfCookie != "" {
+		req.AddCookie(&http.Cookie{Name: auth.CSRFCookieName, Value: csrfCookie})
+	}
+	if csrfHeader != "" {
+		req.Header.Set(auth.CSRFHeaderName, csrfHeader)
+	}
+	return req
+}
+
+func TestSessionAndCSRF(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	serialized, _ := json.Marshal(auth.Session{Username: "jane", CSRFToken: "token"})
+	mockCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(redis.NewStringResult(string(serialized), nil)).AnyTimes()
+	handlers := []gin.HandlerFunc{Session(mockCache), CSRF()}
+
+	tests := []struct {
+		name                   string
+		method                 string
+		csrfCookie, csrfHeader string
+		status                 int
+	}{
+		{"safe method", http.MethodGet, "", "", http.StatusOK},
+		{"matching tokens", http.MethodPost, "token", "token", http.StatusOK},
+		{"missing header", http.MethodPost, "token", "", http.StatusForbidden},
+		{"header not matching cookie", http.MethodPost, "token", "other", http.StatusForbidden},
+		{"cookie and header not matching session", http.MethodDelete, "other", "other", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		var username string
+		w := serve(sessionRequest(tt.method, tt.csrfCookie, tt.csrfHeader), append(handlers, func(c *gin.Context) { username = c.GetString("username") })...)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		if tt.status == http.StatusOK {
+			assert.Equal(t, "jane", username, tt.name)
+		}
+	}
+}
+
+func TestSessionIgnoredWithAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Neither the session is loaded nor is a CSRF token required
+	req := sessionRequest(http.MethodPost, "", "")
+	req.Header.Set("Authorization", "Bearer token")
+	assert.Equal(t, http.StatusOK, serve(req, Session(cache.NewMockCache(ctrl)), CSRF()).Code)
+}
+
+func TestSessionUnknown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	mockCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(redis.NewStringResult("", redis.Nil))
+
+	// An unknown session leaves the request unauthenticated for JWTAuth to reject
+	var authenticated bool
+	w := serve(sessionRequest(http.MethodPost, "", ""), Session(mockCache), CSRF(), func(c *gin.Context) { _, authenticated = c.Get("session") })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, authenticated)
+}
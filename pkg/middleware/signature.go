@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignatureAuth verifies requests signed with a per client secret and rejects
+// nonces that were already seen within the clock skew window.
+func SignatureAuth(redisClient cache.Cache, keys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signed, err := auth.VerifyRequest(c.Request, keys, time.Now())
+		if errors.Is(err, auth.ErrBodyTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		// A nonce only has to be remembered for as long as its timestamp is accepted
+		nonceKey := "signature_nonce_" + signed.KeyID + "_" + signed.Nonce
+		fresh, err := redisClient.SetNX(context.Background(), nonceKey, 1, 2*auth.MaxSignatureClockSkew).Result()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			c.Abort()
+			return
+		}
+		if !fresh {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Replayed request"})
+			c.Abort()
+			return
+		}
+
+		c.Set("signing_key_id", signed.KeyID)
+		c.Next()
+	}
+}
+
+// ClientAuth authenticates the calling application with a signed request when
+// a signature is present and with the static API key otherwise. Setting
+// REQUIRE_REQUEST_SIGNING=true disables the API key.
+func ClientAuth(redisClient cache.Cache) gin.HandlerFunc {
+	apiKeyAuth := APIKeyAuth()
+	signatureAuth := SignatureAuth(redisClient, auth.LoadSigningKeys())
+	requireSigning := os.Getenv("REQUIRE_REQUEST_SIGNING") == "true"
+
+	return func(c *gin.Context) {
+		if c.GetHeader(auth.SignatureHeader) != "" {
+			signatureAuth(c)
+			return
+		}
+		if requireSigning {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		apiKeyAuth(c)
+	}
+}
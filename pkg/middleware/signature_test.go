@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"golang-rest-api-template/pkg/auth"
+	"golang-rest-api-template/pkg/cache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve runs req through handlers followed by a handler answering 200
+func serve(req *http.Request, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/*path", append(handlers, func(c *gin.Context) { c.Status(http.StatusOK) })...)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func signedRequest(t *testing.T, body string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/books", strings.NewReader(body))
+	if err := auth.SignRequest(req, "job-runner", "s3cret", []string{"Content-Type"}); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+	return req
+}
+
+func TestClientAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	t.Setenv("API_SECRET_KEY", "key")
+	t.Setenv("API_SIGNING_KEYS", "job-runner:s3cret")
+	t.Setenv("REQUIRE_REQUEST_SIGNING", "")
+	clientAuth := ClientAuth(mockCache)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	req.Header.Set("X-API-Key", "key")
+	assert.Equal(t, http.StatusOK, serve(req, clientAuth).Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	req.Header.Set("X-API-Key", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, serve(req, clientAuth).Code)
+
+	// A signature is verified instead of the API key
+	req = signedRequest(t, `{}`)
+	req.Header.Set("X-API-Key", "key")
+	req.Header.Set(auth.SignatureHeader, `keyId="job-runner",headers="content-type",signature="forged"`)
+	assert.Equal(t, http.StatusUnauthorized, serve(req, clientAuth).Code)
+}
+
+func TestClientAuthRequiresSigning(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	t.Setenv("API_SECRET_KEY", "key")
+	t.Setenv("API_SIGNING_KEYS", "job-runner:s3cret")
+	t.Setenv("REQUIRE_REQUEST_SIGNING", "true")
+	clientAuth := ClientAuth(mockCache)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/books", nil)
+	req.Header.Set("X-API-Key", "key")
+	assert.Equal(t, http.StatusUnauthorized, serve(req, clientAuth).Code)
+
+	mockCache.EXPECT().SetNX(gomock.Any(), gomock.Any(), 1, 2*auth.MaxSignatureClockSkew).Return(redis.NewBoolResult(true, nil))
+	assert.Equal(t, http.StatusOK, serve(signedRequest(t, `{}`), clientAuth).Code)
+}
+
+func TestSignatureAuthRejectsReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cache.NewMockCache(ctrl)
+	signatureAuth := SignatureAuth(mockCache, map[string]string{"job-runner": "s3cret"})
+
+	req := signedRequest(t, `{"title":"Dune"}`)
+	nonceKey := "signature_nonce_job-runner_" + req.Header.Get(auth.SignatureNonceHeader)
+	gomock.InOrder(
+		mockCache.EXPECT().SetNX(gomock.Any(), nonceKey, 1, 2*auth.MaxSignatureClockSkew).Return(redis.NewBoolResult(true, nil)),
+		mockCache.EXPECT().SetNX(gomock.Any(), nonceKey, 1, 2*auth.MaxSignatureClockSkew).Return(redis.NewBoolResult(false, nil)),
+	)
+
+	assert.Equal(t, http.StatusOK, serve(req, signatureAuth).Code)
+
+	replayed, _ := http.NewRequest(http.MethodPost, "/api/v1/books", strings.NewReader(`{"title":"Dune"}`))
+	replayed.Header = req.Header.Clone()
+	w := serve(replayed, signatureAuth)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Replayed request")
+}
+
+func TestSignatureAuthRejectsLargeBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	signatureAuth := SignatureAuth(cache.NewMockCache(ctrl), map[string]string{"job-runner": "s3cret"})
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/books", strings.NewReader(strings.Repeat("x", auth.MaxSignedBodySize+1)))
+	req.Header = signedRequest(t, `{}`).Header
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(req, signatureAuth).Code)
+}
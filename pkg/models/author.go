@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type Author struct {
+	ID   uint   `json:"id" gorm:"primary_key"`
+	Name string `json:"name"`
+	// NameKey tells different spellings of the same name apart from different names
+	NameKey   string    `json:"-" gorm:"uniqueIndex"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CreateAuthor holds all editable fields of an author, PUT requests take it too
+type CreateAuthor struct {
+	Name string `json:"name" binding:"required,max=255"`
+	Bio  string `json:"bio" binding:"max=10000"`
+}
+
+// NameKey reduces the name of an author or publisher to lowercase letters and
+// digits, so that spellings like "J.R.R. Tolkien" and "J. R. R. Tolkien" match
+func NameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func (a *Author) BeforeSave(*gorm.DB) error {
+	a.NameKey = NameKey(a.Name)
+	return nil
+}
+
+// Roles of the people credited for a book
+const (
+	AuthorRoleAuthor     = "author"
+	AuthorRoleEditor     = "editor"
+	AuthorRoleTranslator = "translator"
+)
+
+// BookAuthor credits an author for a book in a role. A person can have
+// several roles for the same book.
+type BookAuthor struct {
+	BookID   uint   `json:"book_id" gorm:"primaryKey;autoIncrement:false"`
+	AuthorID uint   `json:"author_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Role     string `json:"role" gorm:"primaryKey"`
+	// Position orders the credits of a book, starting at 0
+	Position int `json:"position"`
+	// Purging a book drops its credits, authors can't be deleted while credited
+	Book   *Book   `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Author *Author `json:"author,omitempty" gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (BookAuthor) TableName() string {
+	return "books_authors"
+}
+
+// BookAuthorCredit is one entry of the ordered list of credits of a book
+type BookAuthorCredit struct {
+	AuthorID uint `json:"author_id" binding:"required"`
+	// Role defaults to author
+	Role string `json:"role" binding:"omitempty,oneof=author editor translator"`
+}
+
+// SetBookAuthors replaces the credits of a book, in order
+type SetBookAuthors struct {
+	Authors []BookAuthorCredit `json:"authors" binding:"required,min=1,max=100,dive"`
+}
@@ -1,21 +1,170 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Book struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
+	ID          uint       `json:"id" gorm:"primary_key"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	Genre       string     `json:"genre" gorm:"index"`
+	Language    string     `json:"language" gorm:"index"`
+	PublishedAt *time.Time `json:"published_at" gorm:"index"`
+	// ISBN10 is only set for books that also have one, ISBN13 is unique among books not in the trash
+	ISBN10      string `json:"isbn10" gorm:"size:10"`
+	ISBN13      string `json:"isbn13" gorm:"size:13"`
+	Publisher   string `json:"publisher"`
+	PageCount   int    `json:"page_count"`
+	Description string `json:"description"`
+	Edition     string `json:"edition"`
+	// PublisherID links the publisher Publisher names, it follows changes of the name
+	PublisherID *uint `json:"publisher_id" gorm:"index"`
+	// SeriesVolume orders the books of the series, e.g. 1.5 for a novella between volumes 1 and 2
+	SeriesID     *uint    `json:"series_id" gorm:"index"`
+	SeriesVolume *float64 `json:"series_volume"`
+	// WorkID groups the editions of the same work, it is changed by merging and splitting works
+	WorkID *uint `json:"work_id" gorm:"index"`
+	// CoverKeys are the blob keys of the cover image by size, Covers their URLs
+	CoverKeys map[string]string `json:"-" gorm:"serializer:json"`
+	Covers    map[string]string `json:"covers,omitempty" gorm:"-"`
+	// Version is incremented by every update, for optimistic concurrency control
+	Version   uint      `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	// DeletedAt is set while the book is in the trash
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string"`
+}
+
+// CoverURL is the URL prefix cover images are served under, followed by their blob key
+var CoverURL = "/api/v1/"
+
+// AfterFind fills in the cover URLs
+func (b *Book) AfterFind(*gorm.DB) error {
+	b.SetCoverURLs()
+	return nil
+}
+
+// SetCoverURLs derives the cover URLs from the cover keys
+func (b *Book) SetCoverURLs() {
+	b.Covers = nil
+	if len(b.CoverKeys) == 0 {
+		return
+	}
+	b.Covers = make(map[string]string, len(b.CoverKeys))
+	for size, key := range b.CoverKeys {
+		b.Covers[size] = CoverURL + key
+	}
 }
 
 type CreateBook struct {
-	Title  string `json:"title" binding:"required"`
-	Author string `json:"author" binding:"required"`
+	Title       string     `json:"title" binding:"required"`
+	Author      string     `json:"author" binding:"required"`
+	Genre       string     `json:"genre"`
+	Language    string     `json:"language" binding:"omitempty,bcp47_language_tag"`
+	PublishedAt *time.Time `json:"published_at"`
+	// ISBN10 and ISBN13 may contain hyphens and spaces. Either one fills in the other
+	ISBN10      string `json:"isbn10"`
+	ISBN13      string `json:"isbn13"`
+	Publisher   string `json:"publisher" binding:"max=255"`
+	PageCount   int    `json:"page_count" binding:"omitempty,min=1,max=100000"`
+	Description string `json:"description" binding:"max=10000"`
+	Edition     string `json:"edition" binding:"max=100"`
+	// SeriesVolume requires SeriesID
+	SeriesID     *uint    `json:"series_id"`
+	SeriesVolume *float64 `json:"series_volume" binding:"omitempty,min=0"`
 }
 
+// UpdateBook holds all editable fields of a book, fields left out are cleared
 type UpdateBook struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
+	Title       string     `json:"title" binding:"required"`
+	Author      string     `json:"author" binding:"required"`
+	Genre       string     `json:"genre"`
+	Language    string     `json:"language" binding:"omitempty,bcp47_language_tag"`
+	PublishedAt *time.Time `json:"published_at"`
+	// ISBN10 and ISBN13 may contain hyphens and spaces. Either one fills in the other
+	ISBN10      string `json:"isbn10"`
+	ISBN13      string `json:"isbn13"`
+	Publisher   string `json:"publisher" binding:"max=255"`
+	PageCount   int    `json:"page_count" binding:"omitempty,min=1,max=100000"`
+	Description string `json:"description" binding:"max=10000"`
+	Edition     string `json:"edition" binding:"max=100"`
+	// SeriesVolume requires SeriesID
+	SeriesID     *uint    `json:"series_id"`
+	SeriesVolume *float64 `json:"series_volume" binding:"omitempty,min=0"`
+}
+
+// Operations of a bulk request
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkBooks is a batch of operations on books
+type BulkBooks struct {
+	// Atomic runs all operations in one transaction that is rolled back if any of them fails
+	Atomic     bool                `json:"atomic"`
+	Operations []BulkBookOperation `json:"operations" binding:"required,min=1,max=1000"`
+}
+
+// BulkBookOperation creates a book, replaces the book with ID or deletes it
+type BulkBookOperation struct {
+	Op string `json:"op"`
+	ID uint   `json:"id"`
+	// IfMatch is the ETag of the version being updated or deleted
+	IfMatch string `json:"if_match"`
+	// Book is a CreateBook for create and an UpdateBook for update
+	Book json.RawMessage `json:"book" swaggertype:"object"`
+}
+
+// MergeBooks names the duplicate merged into a book
+type MergeBooks struct {
+	DuplicateID uint `json:"duplicate_id" binding:"required"`
+}
+
+// Actions recorded by book revisions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+	RevisionMerge   = "merge"
+)
+
+// ErrRevisionImmutable is returned when changing or deleting a book revision
+var ErrRevisionImmutable = errors.New("book revisions are immutable")
+
+// BookRevision is an immutable record of a change to a book
+type BookRevision struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	BookID uint   `json:"book_id" gorm:"index"`
+	Action string `json:"action"`
+	// Version is the version of the book after the change
+	Version uint `json:"version"`
+	// Snapshot holds the editable fields of the book after the change
+	Snapshot      json.RawMessage `json:"snapshot" gorm:"type:jsonb" swaggertype:"object"`
+	ChangedFields string          `json:"changed_fields"`
+	// Actor is the user, OAuth2 client or signing key that made the change, empty for API key requests
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id"`
+	// RevertedFrom is the revision a revert restored
+	RevertedFrom *uint `json:"reverted_from,omitempty"`
+	// MergedWith is the other book of a merge: the duplicate on the kept book
+	// and the kept book on the duplicate
+	MergedWith *uint     `json:"merged_with,omitempty"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (BookRevision) BeforeUpdate(*gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+func (BookRevision) BeforeDelete(*gorm.DB) error {
+	return ErrRevisionImmutable
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// States of an export job
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// ExportJob is an export of books written to a file in the background
+type ExportJob struct {
+	ID     string `json:"id" gorm:"primary_key"`
+	Status string `json:"status" gorm:"index"`
+	// Claim identifies the run of the worker that claimed the job, only
+	// that run may update it
+	Claim  string `json:"-"`
+	Format string `json:"format"`
+	// Query holds the filter and sort parameters of the export
+	Query string `json:"query"`
+	// Owner is the user or client that requested the export, only they can see it
+	Owner string `json:"-" gorm:"index"`
+	// Total is the number of matching books, known once the job runs
+	Total     int64  `json:"total"`
+	Processed int64  `json:"processed"`
+	Error     string `json:"error,omitempty"`
+	// Path is the file the export is written to
+	Path string `json:"-"`
+	Size int64  `json:"size"`
+	// Download is the URL of the file once the export is completed
+	Download    string     `json:"download,omitempty" gorm:"-"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// ExpiresAt is when the file is deleted, set once the job is done
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OAuthClient is a machine client allowed to use the client credentials grant
+type OAuthClient struct {
+	ID         uint   `json:"id" gorm:"primary_key"`
+	ClientID   string `json:"client_id" gorm:"uniqueIndex"`
+	SecretHash string `json:"-"`
+	Name       string `json:"name"`
+	Scopes     string `json:"scopes"`
+	// UserID is the admin who registered the client
+	UserID uint `json:"user_id" gorm:"index"`
+	// RevokedAt is set once the client is shut off, its tokens stop working with it
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type CreateOAuthClient struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Publisher struct {
+	ID   uint   `json:"id" gorm:"primary_key"`
+	Name string `json:"name"`
+	// NameKey is unique, books are linked to the publisher whose key their publisher name has
+	NameKey   string    `json:"-" gorm:"uniqueIndex"`
+	Website   string    `json:"website"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CreatePublisher holds all editable fields of a publisher, PUT requests take it too
+type CreatePublisher struct {
+	Name    string `json:"name" binding:"required,max=255"`
+	Website string `json:"website" binding:"omitempty,url,max=255"`
+}
+
+func (p *Publisher) BeforeSave(*gorm.DB) error {
+	p.NameKey = NameKey(p.Name)
+	return nil
+}
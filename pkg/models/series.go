@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Series is a sequence of books meant to be read in order
+type Series struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// CreateSeries holds all editable fields of a series, PUT requests take it too
+type CreateSeries struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=10000"`
+}
@@ -7,10 +7,51 @@ type LoginUser struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// Roles of users, admins may manage the trash. Librarians and admins may
+// group editions into works.
+const (
+	RoleUser      = "user"
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	Username  string    `json:"username" gorm:"unique"`
 	Password  string    `json:"password"`
+	Role      string    `json:"role" gorm:"not null;default:user"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Provider  string    `json:"provider" gorm:"uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
+
+// PersonalAccessToken is a long lived, scope limited credential owned by a user.
+// Only a hash of the token is stored, the token itself is shown once on creation.
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	UserID     uint       `json:"-" gorm:"index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type CreatePersonalAccessToken struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"required,min=1,max=365"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Work groups the editions of a book, e.g. its hardcover, paperback and translations
+type Work struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type UpdateWork struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+// MergeWorks moves all editions of the works into another one and deletes them
+type MergeWorks struct {
+	WorkIDs []uint `json:"work_ids" binding:"required,min=1,max=100"`
+}
+
+// SplitWork moves some editions of a work into a new work
+type SplitWork struct {
+	BookIDs []uint `json:"book_ids" binding:"required,min=1,max=100"`
+	// Title defaults to the title of the first book
+	Title string `json:"title" binding:"max=255"`
+}
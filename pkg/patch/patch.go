@@ -0,0 +1,316 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the supported patch formats
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// ErrTestFailed is returned when a JSON Patch test operation does not match
+var ErrTestFailed = errors.New("patch test operation failed")
+
+// MergePatch applies a JSON Merge Patch to doc. Members of patch set to null
+// are removed, objects are merged recursively and anything else replaces
+// the target.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+		} else {
+			object[key] = mergeValue(object[key], value)
+		}
+	}
+	return object
+}
+
+// Operation is a single JSON Patch operation
+type Operation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	// Value is empty when the member is missing and null when set to null
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies the operations of a JSON Patch to doc. The patch is
+// atomic: if any operation fails, the error is returned and doc is unchanged.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		target, err = apply(target, operation)
+		if err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			return nil, fmt.Errorf("invalid JSON patch operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if len(operation.Value) == 0 {
+			return nil, fmt.Errorf("%s requires a value", operation.Op)
+		}
+		var value interface{}
+		err := json.Unmarshal(operation.Value, &value)
+		return value, err
+	}
+
+	switch operation.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" && isPrefix(from, path) && len(from) < len(path) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		v, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			v = deepCopy(v)
+		}
+		return add(doc, path, v)
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil || !reflect.DeepEqual(actual, expected) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", operation.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %s", token)
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[index]
+		default:
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+	}
+	return doc, nil
+}
+
+// add sets the value at path, inserting into arrays, and returns the new document
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(container)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(container)); err != nil {
+				return nil, err
+			}
+		}
+		container = append(container, nil)
+		copy(container[index+1:], container[index:])
+		container[index] = value
+		return replaceContainer(doc, path[:len(path)-1], container)
+	}
+	return nil, fmt.Errorf("cannot add to %s", strings.Join(path, "/"))
+}
+
+// remove deletes the value at path and returns the new document
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := container[token]; !ok {
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+		delete(container, token)
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container = append(container[:index:index], container[index+1:]...)
+		return replaceContainer(doc, path[:len(path)-1], container)
+	}
+	return nil, fmt.Errorf("path not found: %s", token)
+}
+
+// replaceContainer stores an array that changed length back at path
+func replaceContainer(doc interface{}, path []string, container []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return container, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch grandparent := parent.(type) {
+	case map[string]interface{}:
+		grandparent[token] = container
+	case []interface{}:
+		index, err := arrayIndex(token, len(grandparent)-1)
+		if err != nil {
+			return nil, err
+		}
+		grandparent[index] = container
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, rejecting leading zeros and indexes above max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,78 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7396, appendix A
+	for _, test := range []struct{ doc, patch, result string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		result, err := MergePatch([]byte(test.doc), []byte(test.patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, test.result, string(result), test.patch)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	// Examples from RFC 6902, appendix A
+	for _, test := range []struct{ doc, patch, result string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"foo":"bar","baz":"bar"}`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/foo","value":null}]`, `{"foo":null}`},
+	} {
+		result, err := ApplyJSONPatch([]byte(test.doc), []byte(test.patch))
+		assert.NoError(t, err, test.patch)
+		assert.JSONEq(t, test.result, string(result), test.patch)
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	_, err := ApplyJSONPatch([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+
+	for _, patch := range []string{
+		`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"add","path":"/foo"}]`,
+		`[{"op":"add","path":"/foo/01","value":1}]`,
+		`[{"op":"add","path":"/foo/5","value":1}]`,
+		`[{"op":"move","from":"/foo","path":"/foo/0"}]`,
+		`[{"op":"frobnicate","path":"/foo"}]`,
+		`[{"op":"add","path":"foo","value":1}]`,
+		`{"op":"add"}`,
+	} {
+		_, err := ApplyJSONPatch([]byte(`{"foo":["bar"]}`), []byte(patch))
+		assert.Error(t, err, patch)
+		assert.NotErrorIs(t, err, ErrTestFailed, patch)
+	}
+}
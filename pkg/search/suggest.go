@@ -0,0 +1,342 @@
+// Package search implements an in-memory, typo tolerant autocomplete index.
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Kinds of suggestions
+const (
+	KindTitle  = "title"
+	KindAuthor = "author"
+)
+
+// Suggestion is a completion for the text typed so far
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	// Count is the number of books the suggestion occurs in
+	Count int `json:"count"`
+}
+
+// Match tiers, lower is better
+const (
+	tierPrefix = iota
+	tierWordPrefix
+	tierFuzzy
+)
+
+type entry struct {
+	Suggestion
+	normalized string
+}
+
+// key is a word start within an entry, e.g. "hobbit" for "the hobbit"
+type key struct {
+	text  string
+	entry int
+}
+
+type match struct {
+	entry    int
+	tier     int
+	distance int
+}
+
+// Index answers prefix queries over titles and authors. Words of the query
+// may start anywhere in a word boundary of the text, and queries of four or
+// more characters tolerate a typo (two from eight characters).
+type Index struct {
+	mu       sync.RWMutex
+	entries  []entry
+	keys     []key
+	trigrams map[string][]int
+	builtAt  time.Time
+	stale    bool
+	// generation counts the invalidations, a build only makes the index
+	// fresh if there was none since its data was loaded
+	generation uint64
+	// loads lets concurrent refreshes share one load
+	loads singleflight.Group
+}
+
+// NewIndex returns an empty index that needs to be built
+func NewIndex() *Index {
+	return &Index{stale: true}
+}
+
+// Invalidate marks the index for rebuilding, e.g. after books changed
+func (i *Index) Invalidate() {
+	i.mu.Lock()
+	i.stale = true
+	i.generation++
+	i.mu.Unlock()
+}
+
+// Refresh rebuilds the index from load if it was invalidated or is older
+// than maxAge. Concurrent refreshes wait for the same load. If the index is
+// invalidated while loading, it is rebuilt but stays stale.
+func (i *Index) Refresh(maxAge time.Duration, load func() ([]Suggestion, error)) error {
+	i.mu.RLock()
+	fresh := !i.stale && time.Since(i.builtAt) < maxAge
+	i.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	_, err, _ := i.loads.Do("load", func() (interface{}, error) {
+		generation := i.currentGeneration()
+		suggestions, err := load()
+		if err != nil {
+			return nil, err
+		}
+		i.build(suggestions, generation)
+		return nil, nil
+	})
+	return err
+}
+
+// Build replaces the contents of the index. Suggestions with the same kind
+// and normalized text are merged, adding up their counts.
+func (i *Index) Build(suggestions []Suggestion) {
+	i.build(suggestions, i.currentGeneration())
+}
+
+func (i *Index) currentGeneration() uint64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.generation
+}
+
+// build replaces the contents of the index with suggestions loaded at generation
+func (i *Index) build(suggestions []Suggestion, generation uint64) {
+	merged := make(map[string]int)
+	var entries []entry
+	for _, suggestion := range suggestions {
+		normalized := Normalize(suggestion.Text)
+		if normalized == "" {
+			continue
+		}
+		id := suggestion.Kind + "\x00" + normalized
+		if index, ok := merged[id]; ok {
+			entries[index].Count += suggestion.Count
+			continue
+		}
+		merged[id] = len(entries)
+		entries = append(entries, entry{Suggestion: suggestion, normalized: normalized})
+	}
+
+	var keys []key
+	trigrams := make(map[string][]int)
+	for index, e := range entries {
+		for _, start := range wordStarts(e.normalized) {
+			keys = append(keys, key{text: e.normalized[start:], entry: index})
+		}
+		for _, trigram := range trigramsOf(e.normalized) {
+			postings := trigrams[trigram]
+			if len(postings) == 0 || postings[len(postings)-1] != index {
+				trigrams[trigram] = append(postings, index)
+			}
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a].text < keys[b].text })
+
+	i.mu.Lock()
+	i.entries = entries
+	i.keys = keys
+	i.trigrams = trigrams
+	i.builtAt = time.Now()
+	i.stale = i.generation != generation
+	i.mu.Unlock()
+}
+
+// Suggest returns at most limit suggestions for q, best first
+func (i *Index) Suggest(q string, limit int) []Suggestion {
+	q = Normalize(q)
+	if q == "" || limit < 1 {
+		return []Suggestion{}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	matches := make(map[int]match)
+	add := func(m match) {
+		if existing, ok := matches[m.entry]; !ok || m.tier < existing.tier ||
+			(m.tier == existing.tier && m.distance < existing.distance) {
+			matches[m.entry] = m
+		}
+	}
+
+	// Exact prefixes of the text or of one of its words
+	first := sort.Search(len(i.keys), func(k int) bool { return i.keys[k].text >= q })
+	for k := first; k < len(i.keys) && strings.HasPrefix(i.keys[k].text, q); k++ {
+		tier := tierWordPrefix
+		if len(i.keys[k].text) == len(i.entries[i.keys[k].entry].normalized) {
+			tier = tierPrefix
+		}
+		add(match{entry: i.keys[k].entry, tier: tier})
+	}
+
+	// Only look for typos when the prefixes do not fill the page
+	if maxDistance := allowedTypos(q); len(matches) < limit && maxDistance > 0 {
+		for _, index := range i.candidates(q) {
+			if _, ok := matches[index]; ok {
+				continue
+			}
+			normalized := i.entries[index].normalized
+			best := maxDistance + 1
+			for _, start := range wordStarts(normalized) {
+				if distance := prefixDistance(q, normalized[start:], maxDistance); distance < best {
+					best = distance
+				}
+			}
+			if best <= maxDistance {
+				add(match{entry: index, tier: tierFuzzy, distance: best})
+			}
+		}
+	}
+
+	ranked := make([]match, 0, len(matches))
+	for _, m := range matches {
+		ranked = append(ranked, m)
+	}
+	sort.Slice(ranked, func(a, b int) bool {
+		x, y := ranked[a], ranked[b]
+		if x.tier != y.tier {
+			return x.tier < y.tier
+		}
+		if x.distance != y.distance {
+			return x.distance < y.distance
+		}
+		ex, ey := i.entries[x.entry], i.entries[y.entry]
+		if ex.Count != ey.Count {
+			return ex.Count > ey.Count
+		}
+		if len(ex.normalized) != len(ey.normalized) {
+			return len(ex.normalized) < len(ey.normalized)
+		}
+		if ex.normalized != ey.normalized {
+			return ex.normalized < ey.normalized
+		}
+		return ex.Kind > ey.Kind
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	suggestions := make([]Suggestion, len(ranked))
+	for k, m := range ranked {
+		suggestions[k] = i.entries[m.entry].Suggestion
+	}
+	return suggestions
+}
+
+// candidates returns the entries sharing at least a third of the trigrams of q
+func (i *Index) candidates(q string) []int {
+	trigrams := trigramsOf(q)
+	shared := make(map[int]int)
+	for _, trigram := range trigrams {
+		for _, index := range i.trigrams[trigram] {
+			shared[index]++
+		}
+	}
+	threshold := (len(trigrams) + 2) / 3
+	var candidates []int
+	for index, count := range shared {
+		if count >= threshold {
+			candidates = append(candidates, index)
+		}
+	}
+	return candidates
+}
+
+// Normalize lower cases text and collapses everything but letters and digits to single spaces
+func Normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func allowedTypos(q string) int {
+	switch length := len([]rune(q)); {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	}
+	return 0
+}
+
+// wordStarts returns the byte offsets of the words of normalized text
+func wordStarts(normalized string) []int {
+	starts := []int{0}
+	for index, r := range normalized {
+		if r == ' ' {
+			starts = append(starts, index+1)
+		}
+	}
+	return starts
+}
+
+// trigramsOf returns the trigrams of the words of normalized text, padded so
+// that word starts get trigrams of their own
+func trigramsOf(normalized string) []string {
+	var trigrams []string
+	for _, word := range strings.Fields(normalized) {
+		runes := []rune("  " + word)
+		for k := 0; k+3 <= len(runes); k++ {
+			trigrams = append(trigrams, string(runes[k:k+3]))
+		}
+	}
+	return trigrams
+}
+
+// prefixDistance is the smallest edit distance between q and a prefix of
+// text, or maxDistance+1 if it exceeds maxDistance. Swapping two adjacent
+// characters counts as a single edit.
+func prefixDistance(q, text string, maxDistance int) int {
+	a, b := []rune(q), []rune(text)
+	if len(b) > len(a)+maxDistance {
+		b = b[:len(a)+maxDistance]
+	}
+
+	beforePrevious := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for k := 1; k <= len(a); k++ {
+		current[0] = k
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[k-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if k > 1 && j > 1 && a[k-1] == b[j-2] && a[k-2] == b[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+
+	// q has been consumed, any remainder of text is the completion
+	best := maxDistance + 1
+	for _, distance := range previous {
+		best = min(best, distance)
+	}
+	return best
+}
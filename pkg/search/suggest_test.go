@@ -0,0 +1,132 @@
+package search
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testIndex() *Index {
+	index := NewIndex()
+	index.Build([]Suggestion{
+		{Text: "The Hobbit", Kind: KindTitle, Count: 1},
+		{Text: "J. R. R. Tolkien", Kind: KindAuthor, Count: 1},
+		{Text: "J.R.R. Tolkien", Kind: KindAuthor, Count: 1},
+		{Text: "The Silmarillion", Kind: KindTitle, Count: 1},
+		{Text: "Hobbes", Kind: KindAuthor, Count: 1},
+		{Text: "Leviathan", Kind: KindTitle, Count: 1},
+	})
+	return index
+}
+
+func texts(suggestions []Suggestion) []string {
+	result := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		result[i] = suggestion.Text
+	}
+	return result
+}
+
+func TestSuggestPrefixes(t *testing.T) {
+	index := testIndex()
+
+	// Whole text prefixes rank before word prefixes
+	assert.Equal(t, []string{"Hobbes", "The Hobbit"}, texts(index.Suggest("hob", 10)))
+	assert.Equal(t, []string{"The Hobbit"}, texts(index.Suggest("the hob", 10)))
+	assert.Len(t, index.Suggest("hob", 1), 1)
+	assert.Empty(t, index.Suggest("  ", 10))
+}
+
+func TestSuggestMergesDuplicates(t *testing.T) {
+	suggestions := testIndex().Suggest("tolk", 10)
+
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, 2, suggestions[0].Count)
+	assert.Equal(t, KindAuthor, suggestions[0].Kind)
+}
+
+func TestSuggestToleratesTypos(t *testing.T) {
+	index := testIndex()
+
+	assert.Equal(t, []string{"J. R. R. Tolkien"}, texts(index.Suggest("tolkein", 10)))
+	assert.Equal(t, []string{"The Silmarillion"}, texts(index.Suggest("silmarilion", 10)))
+	assert.Equal(t, []string{"Leviathan"}, texts(index.Suggest("lewiath", 10)))
+	// Short queries must match exactly
+	assert.Empty(t, index.Suggest("hpb", 10))
+}
+
+func TestPrefixDistance(t *testing.T) {
+	assert.Equal(t, 0, prefixDistance("hob", "hobbit", 1))
+	assert.Equal(t, 1, prefixDistance("hobit", "hobbit", 1))
+	assert.Equal(t, 2, prefixDistance("xyzzy", "hobbit", 1))
+}
+
+func TestRefresh(t *testing.T) {
+	index := NewIndex()
+	loads := 0
+	load := func() ([]Suggestion, error) {
+		loads++
+		return []Suggestion{{Text: "Dune", Kind: KindTitle, Count: 1}}, nil
+	}
+
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.Equal(t, 1, loads)
+
+	index.Invalidate()
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.Equal(t, 2, loads)
+
+	index.Invalidate()
+	assert.Error(t, index.Refresh(time.Minute, func() ([]Suggestion, error) { return nil, errors.New("down") }))
+	assert.Equal(t, []string{"Dune"}, texts(index.Suggest("du", 10)))
+}
+
+func TestRefreshInvalidatedWhileLoading(t *testing.T) {
+	index := NewIndex()
+	loads := 0
+	load := func() ([]Suggestion, error) {
+		loads++
+		if loads == 1 {
+			// A book changes after it was read
+			index.Invalidate()
+		}
+		return []Suggestion{{Text: "Dune", Kind: KindTitle, Count: 1}}, nil
+	}
+
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.Equal(t, []string{"Dune"}, texts(index.Suggest("du", 10)))
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.NoError(t, index.Refresh(time.Minute, load))
+	assert.Equal(t, 2, loads)
+}
+
+func TestRefreshConcurrently(t *testing.T) {
+	index := NewIndex()
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() ([]Suggestion, error) {
+		loads.Add(1)
+		<-release
+		return []Suggestion{{Text: "Dune", Kind: KindTitle, Count: 1}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for k := 0; k < 10; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, index.Refresh(time.Minute, load))
+		}()
+	}
+	// Let the refreshes pile up behind the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	assert.NoError(t, store.Put(ctx, "covers/1/large.jpg", strings.NewReader("first")))
+	assert.NoError(t, store.Put(ctx, "covers/1/large.jpg", strings.NewReader("second")))
+
+	blob, size, err := store.Open(ctx, "covers/1/large.jpg")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(blob)
+		blob.Close()
+		assert.Equal(t, "second", string(data))
+		assert.Equal(t, int64(6), size)
+	}
+
+	assert.NoError(t, store.Delete(ctx, "covers/1/large.jpg"))
+	assert.NoError(t, store.Delete(ctx, "covers/1/large.jpg"))
+	_, _, err = store.Open(ctx, "covers/1/large.jpg")
+	assert.Equal(t, ErrNotFound, err)
+	_, _, err = store.Open(ctx, "covers/1")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "../secret", "/etc/passwd", "covers/../../secret", "covers//1"} {
+		assert.Equal(t, ErrInvalidKey, store.Put(ctx, key, strings.NewReader("x")), key)
+		_, _, err := store.Open(ctx, key)
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+}
@@ -0,0 +1,35 @@
+// Package storage keeps binary objects such as cover images behind a
+// pluggable blob store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// Errors returned by blob stores
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore stores blobs by key. Keys are slash separated relative paths
+// such as "covers/1/large.jpg".
+type BlobStore interface {
+	// Put stores the data read from r under key, replacing any blob stored under it
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob stored under key, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete removes the blob stored under key, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that are not clean relative paths
+func checkKey(key string) error {
+	if !fs.ValidPath(key) || key == "." {
+		return ErrInvalidKey
+	}
+	return nil
+}